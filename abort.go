@@ -0,0 +1,73 @@
+package srv
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrAborted may be returned by a HandlerE that has aborted the request with
+// Abort, the response having been written HandleErr then does not pass it on
+// to its error handler.
+var ErrAborted = errors.New(pkg + ": request aborted")
+
+// Abort writes an error response of status with the body msg, or the status
+// text when msg is empty, and marks the request as aborted such that Mware
+// that wrap the handler may find with Aborted that the response is an error
+// of the handlers chosing, so skipping any work that depends upon its body.
+// The caller should return after calling Abort, neither calling the next
+// handler nor writing any more to the response. A guard that rejects requests
+// without credentials is then written as:
+//
+//	func Guard(next http.HandlerFunc) http.HandlerFunc {
+//		return func(res http.ResponseWriter, req *http.Request) {
+//			if req.Header.Get("Authorization") == "" {
+//				srv.Abort(res, http.StatusUnauthorized, "")
+//				return
+//			}
+//			next(res, req)
+//		}
+//	}
+//
+// The request is marked upon every writer of the package found by unwrapping
+// res, those returned by NewResponseWriter included, when there are none the
+// response is written but Aborted can not report it. The middleware of the
+// package heed the mark: ETag does not tag the response, Compress does not
+// compress it, Session does not save the session and Logger notes that the
+// request was aborted.
+func Abort(res http.ResponseWriter, status int, msg string) {
+	for w := res; w != nil; w = unwrap(w) {
+		if a, ok := w.(aborter); ok {
+			a.abort()
+		}
+	}
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	http.Error(res, msg, status)
+}
+
+// Aborted reports whether the request of the response res was aborted with
+// Abort.
+func Aborted(res http.ResponseWriter) bool {
+	for w := res; w != nil; w = unwrap(w) {
+		if a, ok := w.(aborter); ok {
+			return a.isAborted()
+		}
+	}
+	return false
+}
+
+// aborter is implemented by the writers of the package that record whether the
+// request was aborted.
+type aborter interface {
+	abort()
+	isAborted() bool
+}
+
+// unwrap returns the writer wrapped by res, or nil if there is none.
+func unwrap(res http.ResponseWriter) http.ResponseWriter {
+	if u, ok := res.(interface{ Unwrap() http.ResponseWriter }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
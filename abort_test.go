@@ -0,0 +1,35 @@
+package srv
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAbortHeededByMiddleware(t *testing.T) {
+	var logs strings.Builder
+	msg := strings.Repeat("forbidden ", 200)
+	r := NewRouter().Use(stack(&logs)...).Add(Handle("/x", func(res http.ResponseWriter, req *http.Request) {
+		SessionFrom(req.Context()).Set("user", "x")
+		// A 200, which ETag would otherwise tag.
+		Abort(res, http.StatusOK, msg)
+		if !Aborted(res) {
+			t.Error("Aborted: got false")
+		}
+	}))
+	req := httptest.NewRequest("GET", "/x", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := TestRouter(r, req)
+	if rec.Body.String() != msg+"\n" {
+		t.Errorf("got body %q", rec.Body)
+	}
+	for _, h := range []string{"ETag", "Content-Encoding", "Set-Cookie"} {
+		if v := rec.Header().Get(h); v != "" {
+			t.Errorf("aborted response has %s %q", h, v)
+		}
+	}
+	if !strings.Contains(logs.String(), "aborted") {
+		t.Errorf("Logger: abort not logged: %q", logs.String())
+	}
+}
@@ -0,0 +1,127 @@
+package srv
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BasicAuth returns an Mware that requires requests to carry basic auth
+// credentials for which verify returns true, responding otherwise with a 401
+// Unauthorized that challenges the client for the given realm.
+func BasicAuth(realm string, verify func(user, pass string) bool) Mware {
+	challenge := "Basic realm=" + strconv.Quote(realm)
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			user, pass, ok := req.BasicAuth()
+			if !ok || !verify(user, pass) {
+				res.Header().Set("WWW-Authenticate", challenge)
+				http.Error(res, http.StatusText(http.StatusUnauthorized),
+					http.StatusUnauthorized)
+				return
+			}
+			next(res, req)
+		}
+	}
+}
+
+// BasicAuthStatic returns a BasicAuth Mware that accepts the user and password
+// pairs given in creds, the comparison is made in constant time.
+func BasicAuthStatic(realm string, creds map[string]string) Mware {
+	hashes := make(map[string][32]byte, len(creds))
+	for user, pass := range creds {
+		hashes[user] = sha256.Sum256([]byte(pass))
+	}
+	return BasicAuth(realm, func(user, pass string) bool {
+		want, ok := hashes[user]
+		got := sha256.Sum256([]byte(pass))
+		match := subtle.ConstantTimeCompare(got[:], want[:]) == 1
+		return ok && match
+	})
+}
+
+// SignatureConfig configures the VerifySignature Mware.
+type SignatureConfig struct {
+	// Header carries the signature, "X-Signature" by default.
+	Header string
+	// Prefix is removed from the header value before it is decoded, as in
+	// GitHub's "sha256=".
+	Prefix string
+	// Secret is the HMAC key.
+	Secret []byte
+	// Key when set returns the HMAC key for the request, in place of
+	// Secret, an error rejecting the request.
+	Key func(*http.Request) ([]byte, error)
+	// Hash is the hash function, sha256.New by default.
+	Hash func() hash.Hash
+	// MaxBytes limits the body when it has not already been buffered by
+	// BufferBody, DefaultMaxBody by default.
+	MaxBytes int64
+}
+
+// VerifySignature returns an Mware that computes an HMAC of the raw request
+// body and compares it, in constant time, to the hex or base64 encoded
+// signature given in the configured header, responding with a 401
+// Unauthorized on mismatch. The body is buffered, as by BufferBody, so that
+// it remains available to the handler. It panics if neither Secret nor Key is
+// set, and a request for which Key returns an empty key is rejected, as any
+// client could otherwise sign its own requests.
+func VerifySignature(cfg SignatureConfig) Mware {
+	if len(cfg.Secret) == 0 && cfg.Key == nil {
+		panic(pkg + ": VerifySignature: neither Secret nor Key set")
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-Signature"
+	}
+	if cfg.Hash == nil {
+		cfg.Hash = sha256.New
+	}
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = DefaultMaxBody
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		verify := func(res http.ResponseWriter, req *http.Request) {
+			body, _ := BufferedBody(req)
+			key := cfg.Secret
+			if cfg.Key != nil {
+				var err error
+				if key, err = cfg.Key(req); err != nil || len(key) == 0 {
+					unauthorized(res)
+					return
+				}
+			}
+			sig := strings.TrimPrefix(req.Header.Get(cfg.Header), cfg.Prefix)
+			got, err := hex.DecodeString(sig)
+			if err != nil {
+				got, err = base64.StdEncoding.DecodeString(sig)
+			}
+			mac := hmac.New(cfg.Hash, key)
+			mac.Write(body)
+			if err != nil || sig == "" || !hmac.Equal(got, mac.Sum(nil)) {
+				unauthorized(res)
+				return
+			}
+			next(res, req)
+		}
+		buffered := BufferBody(cfg.MaxBytes)(verify)
+		return func(res http.ResponseWriter, req *http.Request) {
+			if _, ok := BufferedBody(req); ok {
+				verify(res, req)
+				return
+			}
+			buffered(res, req)
+		}
+	}
+}
+
+// unauthorized writes a 401 Unauthorized.
+func unauthorized(res http.ResponseWriter) {
+	http.Error(res, http.StatusText(http.StatusUnauthorized),
+		http.StatusUnauthorized)
+}
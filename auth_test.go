@@ -0,0 +1,28 @@
+package srv
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifySignatureNoKey(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("VerifySignature without a key did not panic")
+		}
+	}()
+	VerifySignature(SignatureConfig{})
+}
+
+func TestVerifySignatureEmptyKey(t *testing.T) {
+	route := Handle("/", http.NotFound).Wrap(VerifySignature(SignatureConfig{
+		Key: func(*http.Request) ([]byte, error) { return nil, nil },
+	}))
+	req := httptest.NewRequest("POST", "/", nil)
+	// The HMAC-SHA256 of an empty body under an empty key.
+	req.Header.Set("X-Signature", "b613679a0814d9ec772f95d778c35fc5ff1697c493715653c6c712144292c5ad")
+	if rec := TestRoute(route, req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d want 401", rec.Code)
+	}
+}
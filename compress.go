@@ -0,0 +1,336 @@
+package srv
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressOption configures the Compress Mware.
+type CompressOption func(*compressConfig)
+
+type compressConfig struct {
+	level     int
+	min       int
+	types     []string
+	encodings []string
+	news      map[string]func(io.Writer) Compressor
+	pools     map[string]*sync.Pool
+}
+
+// CompressLevel sets the compression level, as defined by compress/flate,
+// flate.DefaultCompression by default. It panics if level is not one that
+// compress/flate accepts, from flate.HuffmanOnly to flate.BestCompression.
+func CompressLevel(level int) CompressOption {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		panic(fmt.Sprintf("%s: CompressLevel: invalid level %d", pkg, level))
+	}
+	return func(c *compressConfig) { c.level = level }
+}
+
+// CompressMinSize sets the size in bytes below which response bodies are not
+// compressed, 1024 by default.
+func CompressMinSize(n int) CompressOption {
+	return func(c *compressConfig) { c.min = n }
+}
+
+// CompressTypes sets the media types that may be compressed, a type of the
+// form "text/*" matches all sub types. By default text, JSON, JavaScript, XML
+// and SVG are compressed.
+func CompressTypes(types ...string) CompressOption {
+	return func(c *compressConfig) { c.types = types }
+}
+
+// DefaultCompressTypes are the media types compressed by default.
+var DefaultCompressTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// compressible reports whether the media type of contentType is allowed.
+func (c *compressConfig) compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range c.types {
+		if t == mt {
+			return true
+		}
+		if base, ok := strings.CutSuffix(t, "*"); ok && strings.HasPrefix(mt, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compressor is a writer that applies a content coding, it is implemented by
+// *gzip.Writer and *flate.Writer amongst others. Compressors are pooled, Reset
+// preparing one for reuse.
+type Compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// CompressEncoding adds a content coding, such as brotli, to those offered by
+// Compress, fn returning a Compressor that writes to w. Encodings added are
+// preferred, in the order added, over gzip and deflate when the client accepts
+// them with equal q values. This allows encodings that require a dependency to
+// be used without srv itself depending upon them:
+//
+//	srv.Compress(srv.CompressEncoding("br", func(w io.Writer) srv.Compressor {
+//		return brotli.NewWriter(w)
+//	}))
+func CompressEncoding(name string, fn func(w io.Writer) Compressor) CompressOption {
+	return func(c *compressConfig) {
+		if c.news == nil {
+			c.news = make(map[string]func(io.Writer) Compressor)
+		}
+		if _, ok := c.news[name]; !ok {
+			c.encodings = append(c.encodings, name)
+		}
+		c.news[name] = fn
+	}
+}
+
+// Compress returns an Mware that compresses response bodies with gzip or
+// deflate, or any encoding added with CompressEncoding, according to the
+// requests Accept-Encoding header. Bodies that are smaller than the minimum
+// size, of a media type that is not allowed, or to which the handler has
+// already applied a Content-Encoding are left as is, as are partial responses
+// to range requests and responses aborted with Abort.
+// Flushing the response flushes the compressor, and then the underlying
+// writer, so that streamed responses such as server sent events reach the
+// client promptly.
+func Compress(opts ...CompressOption) Mware {
+	cfg := compressConfig{
+		level: flate.DefaultCompression,
+		min:   1024,
+		types: DefaultCompressTypes,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.news == nil {
+		cfg.news = make(map[string]func(io.Writer) Compressor)
+	}
+	defaults := map[string]func(io.Writer) Compressor{
+		"gzip": func(w io.Writer) Compressor {
+			z, _ := gzip.NewWriterLevel(w, cfg.level)
+			return z
+		},
+		"deflate": func(w io.Writer) Compressor {
+			f, _ := flate.NewWriter(w, cfg.level)
+			return f
+		},
+	}
+	for _, name := range []string{"gzip", "deflate"} {
+		if _, ok := cfg.news[name]; !ok {
+			cfg.encodings = append(cfg.encodings, name)
+			cfg.news[name] = defaults[name]
+		}
+	}
+	cfg.pools = make(map[string]*sync.Pool, len(cfg.news))
+	for name, fn := range cfg.news {
+		cfg.pools[name] = &sync.Pool{New: func() any {
+			return fn(io.Discard)
+		}}
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Add("Vary", "Accept-Encoding")
+			enc := acceptEncoding(req.Header.Get("Accept-Encoding"),
+				cfg.encodings...)
+			if enc == "" || req.Method == http.MethodHead {
+				next(res, req)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: res, cfg: &cfg, enc: enc}
+			defer cw.close()
+			next(cw, req)
+		}
+	}
+}
+
+// acceptEncoding returns the encoding from offered that is most preferred by
+// the given Accept-Encoding header, ties being resolved by the order of
+// offered, or an empty string if none are acceptable.
+func acceptEncoding(header string, offered ...string) string {
+	q := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		v := 1.0
+		if k, val, ok := strings.Cut(strings.TrimSpace(params), "="); ok &&
+			strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+				v = f
+			}
+		}
+		q[name] = v
+	}
+	best, bestq := "", 0.0
+	for _, enc := range offered {
+		v, ok := q[enc]
+		if !ok {
+			v, ok = q["*"]
+		}
+		if ok && v > bestq {
+			best, bestq = enc, v
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it is able to decide
+// whether or not the body is to be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg     *compressConfig
+	enc     string
+	buf     []byte
+	status  int
+	decided bool
+	aborted bool
+	w       Compressor
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = code
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.cfg.min {
+			return len(b), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.w != nil {
+		return cw.w.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// decide determines whether the response is to be compressed, writes the
+// header and then any buffered body.
+func (cw *compressWriter) decide(large bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	status := cw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	// A partial response is left as is, its Content-Range counting the
+	// bytes of the uncompressed body.
+	if large && !cw.aborted && h.Get("Content-Encoding") == "" &&
+		status != http.StatusNoContent && status != http.StatusNotModified &&
+		status != http.StatusPartialContent && h.Get("Content-Range") == "" &&
+		cw.cfg.compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", cw.enc)
+		h.Del("Content-Length")
+		cw.w = cw.cfg.pools[cw.enc].Get().(Compressor)
+		cw.w.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.w != nil {
+		_, err = cw.w.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// close writes out anything remaining in the buffer and closes the
+// compressor returning it to its pool.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			return
+		}
+		cw.decide(false)
+	}
+	if cw.w != nil {
+		cw.w.Close()
+		cw.w.Reset(io.Discard)
+		cw.cfg.pools[cw.enc].Put(cw.w)
+		cw.w = nil
+	}
+}
+
+// Flush writes out any buffered data, compressing it if the media type allows
+// regardless of its size, and then flushes the underlying writer.
+func (cw *compressWriter) Flush() {
+	cw.FlushError()
+}
+
+func (cw *compressWriter) FlushError() error {
+	if !cw.decided {
+		if err := cw.decide(true); err != nil {
+			return err
+		}
+	}
+	if cw.w != nil {
+		if err := cw.w.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: %T does not implement http.Hijacker",
+			pkg, cw.ResponseWriter)
+	}
+	cw.decided = true
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use by
+// http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) abort()          { cw.aborted = true }
+func (cw *compressWriter) isAborted() bool { return cw.aborted }
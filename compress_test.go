@@ -0,0 +1,57 @@
+package srv
+
+import (
+	"compress/flate"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressLevel(t *testing.T) {
+	for _, level := range []int{flate.HuffmanOnly, flate.DefaultCompression, flate.BestCompression} {
+		Compress(CompressLevel(level))
+	}
+	for _, level := range []int{flate.HuffmanOnly - 1, flate.BestCompression + 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("CompressLevel(%d): accepted", level)
+				}
+			}()
+			CompressLevel(level)
+		}()
+	}
+}
+
+func TestCompressRange(t *testing.T) {
+	dir := t.TempDir()
+	body := strings.Repeat("a", 5000)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter().Use(Compress()).Add(Static("/static/", dir))
+	req := httptest.NewRequest("GET", "/static/a.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-1999")
+	rec := TestRouter(r, req)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("got status %d want 206", rec.Code)
+	}
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("partial response compressed with %q", enc)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 0-1999/5000" {
+		t.Errorf("Content-Range %q", got)
+	}
+	if rec.Body.String() != body[:2000] {
+		t.Errorf("got %d bytes of body want 2000", rec.Body.Len())
+	}
+
+	req.Header.Del("Range")
+	if rec = TestRouter(r, req); rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("full response not compressed: %v", rec.Header())
+	}
+}
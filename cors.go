@@ -0,0 +1,107 @@
+package srv
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS Mware.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins that may make cross origin requests,
+	// "*" permits any origin and a single "*" within an entry matches any
+	// sequence of characters, "https://*.example.com".
+	AllowedOrigins []string
+	// AllowedMethods lists the methods permitted in preflight requests,
+	// defaulting to GET, HEAD and POST.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers permitted in preflight
+	// requests, when empty the requested headers are echoed back.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers made available to the
+	// client.
+	ExposedHeaders []string
+	// AllowCredentials permits requests that include credentials, the
+	// request origin is then always echoed back rather than "*".
+	AllowCredentials bool
+	// MaxAge is the duration for which a preflight response may be cached.
+	MaxAge time.Duration
+}
+
+// allowed reports whether origin matches any of the configured origins.
+func (c *CORSConfig) allowed(origin string) (ok, wildcard bool) {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			return true, true
+		}
+		if o == origin {
+			return true, false
+		}
+		if pre, suf, found := strings.Cut(o, "*"); found &&
+			len(origin) >= len(pre)+len(suf) &&
+			strings.HasPrefix(origin, pre) &&
+			strings.HasSuffix(origin, suf) {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// CORS returns an Mware that sets the cross origin resource sharing headers on
+// responses to permitted origins and which responds to preflight requests with
+// a 204 No Content, without calling the wrapped handler.
+func CORS(cfg CORSConfig) Mware {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	if methods == "" {
+		methods = "GET, HEAD, POST"
+	}
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge / time.Second))
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			h := res.Header()
+			h.Add("Vary", "Origin")
+			origin := req.Header.Get("Origin")
+			preflight := req.Method == http.MethodOptions &&
+				req.Header.Get("Access-Control-Request-Method") != ""
+			ok, wildcard := cfg.allowed(origin)
+			if origin == "" || !ok {
+				if preflight {
+					res.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next(res, req)
+				return
+			}
+			if wildcard && !cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if exposed != "" {
+					h.Set("Access-Control-Expose-Headers", exposed)
+				}
+				next(res, req)
+				return
+			}
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			} else if rh := req.Header.Get("Access-Control-Request-Headers"); rh != "" {
+				h.Set("Access-Control-Allow-Headers", rh)
+			}
+			if cfg.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			res.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
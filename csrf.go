@@ -0,0 +1,109 @@
+package srv
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+)
+
+// CSRFConfig configures the CSRF Mware, zero values take the defaults given.
+type CSRFConfig struct {
+	// CookieName is the name of the token cookie, "csrf_token".
+	CookieName string
+	// HeaderName is the request header that may carry the token,
+	// "X-CSRF-Token".
+	HeaderName string
+	// FieldName is the form field that may carry the token, "csrf_token".
+	FieldName string
+	// Path is the path of the cookie, "/".
+	Path string
+	// MaxAge is the lifetime of the cookie, 12 hours.
+	MaxAge time.Duration
+	// Secure restricts the cookie to https.
+	Secure bool
+	// SameSite is the cookies SameSite attribute, http.SameSiteLaxMode.
+	SameSite http.SameSite
+}
+
+type csrfKey struct{}
+
+// CSRF returns an Mware that protects against cross site request forgery with
+// the double submit cookie pattern. Every response carries a token cookie,
+// the token also being made available to handlers by CSRFToken, and requests
+// made with any method other than GET, HEAD, OPTIONS or TRACE are rejected
+// with a 403 Forbidden unless they carry the same token in the configured
+// header or form field, the tokens being compared in constant time.
+func CSRF(cfg CSRFConfig) Mware {
+	if cfg.CookieName == "" {
+		cfg.CookieName = "csrf_token"
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = "X-CSRF-Token"
+	}
+	if cfg.FieldName == "" {
+		cfg.FieldName = "csrf_token"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 12 * time.Hour
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			token := ""
+			if c, err := req.Cookie(cfg.CookieName); err == nil && len(c.Value) == 43 {
+				token = c.Value
+			}
+			switch req.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			default:
+				sent := req.Header.Get(cfg.HeaderName)
+				if sent == "" {
+					sent = req.PostFormValue(cfg.FieldName)
+				}
+				if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+					http.Error(res, http.StatusText(http.StatusForbidden),
+						http.StatusForbidden)
+					return
+				}
+			}
+			if token == "" {
+				token = newCSRFToken()
+			}
+			http.SetCookie(res, &http.Cookie{
+				Name:     cfg.CookieName,
+				Value:    token,
+				Path:     cfg.Path,
+				MaxAge:   int(cfg.MaxAge / time.Second),
+				Secure:   cfg.Secure,
+				HttpOnly: true,
+				SameSite: cfg.SameSite,
+			})
+			res.Header().Add("Vary", "Cookie")
+			ctx := context.WithValue(req.Context(), csrfKey{}, token)
+			next(res, req.WithContext(ctx))
+		}
+	}
+}
+
+// CSRFToken returns the CSRF token of the request, for inclusion in forms and
+// templates, or an empty string if the request has not passed through the
+// CSRF Mware.
+func CSRFToken(req *http.Request) string {
+	token, _ := req.Context().Value(csrfKey{}).(string)
+	return token
+}
+
+// newCSRFToken returns 32 random bytes base64 encoded, 43 characters.
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,33 @@
+// Package srv composes http.HandlerFuncs, wrapped in middleware, into an
+// http.ServeMux. Routes are created with Handle, collected into Groups, which
+// may share a prefix and middleware, and finally added to a Router which
+// composes them all onto the mux.
+//
+// # Middleware order
+//
+// An Mware wraps a handler, code that it runs before calling the next handler
+// sees the request before any Mware that it wraps, code run after sees the
+// response after them. For a single request the layers, from the outermost,
+// first to see the request, to the innermost, are:
+//
+//  1. Mware added with Router.Use, in the order added.
+//  2. Mware added with Router.Wrap, the last added being outermost.
+//  3. PatternMware added with Router.WrapPattern, the last added being
+//     outermost.
+//  4. Mware of the Groups that contain the route, from the outermost group to
+//     the innermost, within each group the last added by Wrap being outermost.
+//  5. Mware applied to the Route by Handle and Route.Wrap, the last given
+//     being outermost.
+//  6. The handler.
+//
+// As such, for a router r, a group g and a route h created with
+//
+//	r.Use(a).Wrap(b)
+//	g.Wrap(c)
+//	h := srv.Handle("/", fn, d)
+//
+// a request passes through a, b, c and d in that order before reaching fn,
+// the response returning through d, c, b and a. Chain combines several Mware
+// in the same order as Wrap, where an ordering in which the first given is
+// outermost is wanted use Router.Use.
+package srv
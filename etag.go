@@ -0,0 +1,190 @@
+package srv
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ETagOption configures the ETag Mware.
+type ETagOption func(*etagConfig)
+
+type etagConfig struct {
+	weak bool
+	max  int
+}
+
+// WeakETag generates weak validators, W/"...", in place of strong ones.
+func WeakETag() ETagOption {
+	return func(c *etagConfig) { c.weak = true }
+}
+
+// ETagMaxSize sets the size in bytes above which a response is no longer
+// buffered and is sent without an ETag, 1MB by default.
+func ETagMaxSize(n int) ETagOption {
+	return func(c *etagConfig) { c.max = n }
+}
+
+// ETag returns an Mware that buffers successful responses to GET and HEAD
+// requests, setting an ETag computed from the body, unless the handler has
+// set its own, and responding with a 304 Not Modified when it matches the
+// requests If-None-Match header. Responses that exceed the maximum size or
+// that are flushed by the handler, or aborted with Abort, are streamed without
+// an ETag.
+func ETag(opts ...ETagOption) Mware {
+	cfg := etagConfig{max: 1 << 20}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				next(res, req)
+				return
+			}
+			w := &etagWriter{ResponseWriter: res, cfg: &cfg}
+			next(w, req)
+			w.finish(req)
+		}
+	}
+}
+
+// etagWriter buffers a response so that its ETag may be computed.
+type etagWriter struct {
+	http.ResponseWriter
+	cfg       *etagConfig
+	buf       []byte
+	status    int
+	streaming bool
+	aborted   bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.streaming || code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if len(w.buf)+len(b) > w.cfg.max {
+		if err := w.stream(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	return len(b), nil
+}
+
+// stream stops buffering, writing out the header and anything buffered.
+func (w *etagWriter) stream() error {
+	if w.streaming {
+		return nil
+	}
+	w.streaming = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) > 0 {
+		_, err := w.ResponseWriter.Write(buf)
+		return err
+	}
+	return nil
+}
+
+// finish sets the ETag of a buffered response and writes it, or a 304 Not
+// Modified if it matches the request.
+func (w *etagWriter) finish(req *http.Request) {
+	if w.streaming {
+		return
+	}
+	if w.aborted {
+		w.stream()
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	h := w.Header()
+	if w.status == http.StatusOK {
+		tag := h.Get("ETag")
+		if tag == "" {
+			sum := sha256.Sum256(w.buf)
+			tag = `"` + hex.EncodeToString(sum[:16]) + `"`
+			if w.cfg.weak {
+				tag = "W/" + tag
+			}
+			h.Set("ETag", tag)
+		}
+		if etagMatch(req.Header.Get("If-None-Match"), tag) {
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.stream()
+}
+
+// etagMatch reports whether tag matches any of the entity tags in the given
+// If-None-Match header using the weak comparison function.
+func etagMatch(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush stops the buffering of the response, which is sent without an ETag.
+func (w *etagWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *etagWriter) FlushError() error {
+	if err := w.stream(); err != nil {
+		return err
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: %T does not implement http.Hijacker",
+			pkg, w.ResponseWriter)
+	}
+	w.streaming = true
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use by
+// http.ResponseController.
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *etagWriter) abort()          { w.aborted = true }
+func (w *etagWriter) isAborted() bool { return w.aborted }
@@ -0,0 +1,21 @@
+// Package h2c serves cleartext HTTP/2 for an srv Router. It is a package of its
+// own so that only programs that import it link golang.org/x/net.
+//
+// Cleartext HTTP/2 carries no encryption, it is intended for use only upon a
+// trusted network, as when behind a load balancer that terminates TLS and
+// forwards requests upstream as h2c.
+package h2c
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Handler returns a handler that serves h over cleartext HTTP/2 to clients
+// that request it, either with prior knowledge or by upgrading from HTTP/1.1,
+// and otherwise serves h as usual over HTTP/1.
+func Handler(h http.Handler) http.Handler {
+	return h2c.NewHandler(h, &http2.Server{})
+}
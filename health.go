@@ -0,0 +1,94 @@
+package srv
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ReadyTimeout is the time given to the checks of a Ready route to complete.
+var ReadyTimeout = 2 * time.Second
+
+// errTimedOut is reported for a check that does not complete in time.
+var errTimedOut = errors.New("timed out")
+
+// Health returns a Route that always responds with a 200 OK and a small JSON
+// body.
+func Health(pattern string) *Route {
+	return &Route{pattern: pattern, fn: func(res http.ResponseWriter, req *http.Request) {
+		JSON(res, http.StatusOK, map[string]string{"status": "ok"})
+	}}
+}
+
+// ReadyCheck is a named readiness check, as run by Ready.
+type ReadyCheck struct {
+	name string
+	fn   func() error
+}
+
+// Check returns a readiness check of the given name, the name being prefixed
+// to any error that fn returns or to the report that it timed out.
+func Check(name string, fn func() error) ReadyCheck {
+	return ReadyCheck{name: name, fn: fn}
+}
+
+// Ready returns a Route that runs the given checks concurrently, responding
+// with a 200 OK if all pass within ReadyTimeout or otherwise with a 503
+// Service Unavailable listing the errors of those that failed, each prefixed by
+// the name of its check.
+//
+//	srv.Ready("/ready",
+//		srv.Check("lifecycle", l.Check),
+//		srv.Check("db", db.Ping),
+//	)
+func Ready(pattern string, checks ...ReadyCheck) *Route {
+	return &Route{pattern: pattern, fn: func(res http.ResponseWriter, req *http.Request) {
+		failed := runChecks(checks, ReadyTimeout)
+		if len(failed) > 0 {
+			JSON(res, http.StatusServiceUnavailable, map[string]any{
+				"status": "unavailable",
+				"failed": failed,
+			})
+			return
+		}
+		JSON(res, http.StatusOK, map[string]string{"status": "ok"})
+	}}
+}
+
+// runChecks runs checks concurrently returning the errors of those that fail
+// or that do not complete within timeout, in the order of checks.
+func runChecks(checks []ReadyCheck, timeout time.Duration) []string {
+	errs := make([]chan error, len(checks))
+	for i, check := range checks {
+		errs[i] = make(chan error, 1)
+		go func() {
+			errs[i] <- check.fn()
+		}()
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	var failed []string
+	expired := false
+	for i := range errs {
+		var err error
+		if expired {
+			select {
+			case err = <-errs[i]:
+			default:
+				err = errTimedOut
+			}
+		} else {
+			select {
+			case err = <-errs[i]:
+			case <-deadline.C:
+				expired = true
+				err = errTimedOut
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", checks[i].name, err))
+		}
+	}
+	return failed
+}
@@ -0,0 +1,24 @@
+package srv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunChecksNamed(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	checks := []ReadyCheck{
+		Check("ok", func() error { return nil }),
+		Check("db", func() error { return errors.New("down") }),
+		Check("slow", func() error { <-block; return nil }),
+		Check("slower", func() error { <-block; return nil }),
+	}
+	failed := runChecks(checks, 10*time.Millisecond)
+	want := []string{"db: down", "slow: timed out", "slower: timed out"}
+	if strings.Join(failed, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q want %q", failed, want)
+	}
+}
@@ -0,0 +1,161 @@
+package srv
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseCIDRs parses the given CIDR ranges, "10.0.0.0/8", a bare address being
+// taken as a range containing only itself.
+func ParseCIDRs(cidrs ...string) ([]net.IPNet, error) {
+	nets := make([]net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("%s: invalid address %q", pkg, c)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pkg, err)
+		}
+		nets = append(nets, *n)
+	}
+	return nets, nil
+}
+
+// contains reports whether any of nets contains ip.
+func contains(nets []net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP parses an address that may be given with a port and or in brackets,
+// "[::1]:80", returning nil if it is malformed.
+func parseIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	return net.ParseIP(s)
+}
+
+// ClientIP returns the address of the client that made req, only when the
+// immediate peer is a trusted proxy is X-Forwarded-For consulted, it being
+// walked from right to left skipping trusted proxies until the first address
+// that is not trusted is found. A malformed entry ends the walk, the last
+// valid address being returned, as is nil when RemoteAddr is itself malformed.
+func ClientIP(req *http.Request, trusted []net.IPNet) net.IP {
+	ip := parseIP(req.RemoteAddr)
+	if ip == nil || !contains(trusted, ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		if strings.TrimSpace(hops[i]) == "" {
+			continue
+		}
+		hop := parseIP(hops[i])
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !contains(trusted, ip) {
+			break
+		}
+	}
+	return ip
+}
+
+// IPFilterConfig configures the IPFilter Mware.
+type IPFilterConfig struct {
+	// Allow when not empty permits only the clients within its ranges.
+	Allow []net.IPNet
+	// Deny refuses the clients within its ranges, taking precedence over
+	// Allow.
+	Deny []net.IPNet
+	// TrustedProxies are the proxies from which X-Forwarded-For is trusted
+	// when determining the address of the client.
+	TrustedProxies []net.IPNet
+}
+
+// IPFilter returns an Mware that responds with a 403 Forbidden to clients
+// whose address is within the configured Deny ranges, or when Allow is set is
+// not within its ranges. The client address is that of the immediate peer
+// unless it is a trusted proxy, in which case X-Forwarded-For is consulted.
+func IPFilter(cfg IPFilterConfig) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			ip := ClientIP(req, cfg.TrustedProxies)
+			if ip == nil || contains(cfg.Deny, ip) ||
+				(len(cfg.Allow) > 0 && !contains(cfg.Allow, ip)) {
+				http.Error(res, http.StatusText(http.StatusForbidden),
+					http.StatusForbidden)
+				return
+			}
+			next(res, req)
+		}
+	}
+}
+
+// HTTPSConfig configures the RequireHTTPS Mware.
+type HTTPSConfig struct {
+	// TrustedProxies are the proxies from which X-Forwarded-Proto is trusted
+	// to report that the client connected over TLS.
+	TrustedProxies []net.IPNet
+}
+
+// RequireHTTPS returns an Mware that responds 403 Forbidden to any request not
+// made over TLS, such that an API is never served in cleartext even by
+// mistake. A request that does not itself arrive over TLS is accepted only
+// when its immediate peer is a trusted proxy that reports, as the last value
+// of X-Forwarded-Proto, that the client connected with https. Unlike Redirect
+// it never redirects, so that the two may be used together, Redirect upon the
+// http listener and RequireHTTPS upon the routes that it protects.
+func RequireHTTPS(cfg HTTPSConfig) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			if !isHTTPS(req, cfg.TrustedProxies) {
+				http.Error(res, http.StatusText(http.StatusForbidden),
+					http.StatusForbidden)
+				return
+			}
+			next(res, req)
+		}
+	}
+}
+
+// isHTTPS reports whether req was made over TLS, either directly or, when the
+// immediate peer is trusted, as reported by X-Forwarded-Proto.
+func isHTTPS(req *http.Request, trusted []net.IPNet) bool {
+	if req.TLS != nil {
+		return true
+	}
+	ip := parseIP(req.RemoteAddr)
+	if ip == nil || !contains(trusted, ip) {
+		return false
+	}
+	values := req.Header.Values("X-Forwarded-Proto")
+	if len(values) == 0 {
+		return false
+	}
+	protos := strings.Split(values[len(values)-1], ",")
+	return strings.EqualFold(strings.TrimSpace(protos[len(protos)-1]), "https")
+}
@@ -0,0 +1,46 @@
+package srv
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseCIDRs("10.0.0.0/8", "fd00::/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"no header", "1.2.3.4:80", nil, "1.2.3.4"},
+		{"untrusted peer", "1.2.3.4:80", []string{"5.6.7.8"}, "1.2.3.4"},
+		{"trusted peer", "10.0.0.1:80", []string{"5.6.7.8"}, "5.6.7.8"},
+		{"trusted hops", "10.0.0.1:80", []string{"5.6.7.8, 10.0.0.2, 10.0.0.3"}, "5.6.7.8"},
+		{"spoofed left", "10.0.0.1:80", []string{"9.9.9.9, 5.6.7.8, 10.0.0.2"}, "5.6.7.8"},
+		{"several headers", "10.0.0.1:80", []string{"5.6.7.8", "10.0.0.2"}, "5.6.7.8"},
+		{"all trusted", "10.0.0.1:80", []string{"10.0.0.2, 10.0.0.3"}, "10.0.0.2"},
+		{"empty entries", "10.0.0.1:80", []string{"5.6.7.8, , "}, "5.6.7.8"},
+		{"malformed", "10.0.0.1:80", []string{"5.6.7.8, bogus"}, "10.0.0.1"},
+		{"malformed past client", "10.0.0.1:80", []string{"bogus, 5.6.7.8"}, "5.6.7.8"},
+		{"with port", "10.0.0.1:80", []string{"5.6.7.8:1234"}, "5.6.7.8"},
+		{"ipv6 peer", "[2001:db8::1]:80", nil, "2001:db8::1"},
+		{"ipv6 bracketed", "[fd00::1]:80", []string{"[2001:db8::2]:443"}, "2001:db8::2"},
+		{"ipv6 bare", "[fd00::1]:80", []string{"2001:db8::2"}, "2001:db8::2"},
+		{"ipv6 zone", "[fe80::1%eth0]:80", nil, "fe80::1"},
+		{"malformed remote", "bogus", []string{"5.6.7.8"}, "<nil>"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		for _, v := range tt.xff {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		if got := ClientIP(req, trusted).String(); got != tt.want {
+			t.Errorf("%s: got %s want %s", tt.name, got, tt.want)
+		}
+	}
+}
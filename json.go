@@ -0,0 +1,31 @@
+package srv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// JSON writes v to res JSON encoded with the given status code.
+func JSON(res http.ResponseWriter, status int, v any) error {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	return json.NewEncoder(res).Encode(v)
+}
+
+// DecodeJSON decodes the JSON body of req into v, reading no more than
+// maxBytes, an error is returned if the body is larger, contains fields that v
+// does not or contains more than a single JSON value.
+func DecodeJSON(req *http.Request, v any, maxBytes int64) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, req.Body, maxBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: request body must contain a single JSON value", pkg)
+	}
+	return nil
+}
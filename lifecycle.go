@@ -0,0 +1,75 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Lifecycle coordinates the shutdown of a server with its readiness, such
+// that a load balancer may stop routing to it before it stops serving. Upon
+// SIGINT or SIGTERM, or when the context given to Serve is done, the
+// Lifecycle is marked as no longer ready, failing its Check, it then waits for
+// the pre shutdown delay before the server is shutdown gracefully, in flight
+// requests being given the grace period set by ShutdownTimeout.
+type Lifecycle struct {
+	delay    time.Duration
+	draining atomic.Bool
+}
+
+// NewLifecycle returns a ready Lifecycle that waits for delay between ceasing
+// to be ready and shutting down its server.
+func NewLifecycle(delay time.Duration) *Lifecycle {
+	return &Lifecycle{delay: delay}
+}
+
+// errDraining is returned by the Check of a Lifecycle that is shutting down.
+var errDraining = errors.New("shutting down")
+
+// Ready reports whether the Lifecycle is ready, that is not yet shutting down.
+func (l *Lifecycle) Ready() bool {
+	return !l.draining.Load()
+}
+
+// Check is a readiness check for use with Ready, failing once the Lifecycle
+// has begun to shut down.
+//
+//	srv.Ready("/ready", srv.Check("lifecycle", l.Check))
+func (l *Lifecycle) Check() error {
+	if l.draining.Load() {
+		return errDraining
+	}
+	return nil
+}
+
+// drain marks the Lifecycle as no longer ready and waits for the pre shutdown
+// delay.
+func (l *Lifecycle) drain() {
+	l.draining.Store(true)
+	time.Sleep(l.delay)
+}
+
+// ListenAndServe serves h on addr as does the package level ListenAndServe,
+// the server being shutdown as described by Lifecycle.
+func (l *Lifecycle) ListenAndServe(addr string, h http.Handler, opts ...ServerOption) error {
+	cfg := newServerConfig(opts)
+	server := cfg.server(addr, h)
+	return l.Serve(context.Background(), server, opts...)
+}
+
+// Serve runs server as does the package level Serve until it fails, ctx is
+// done or the process receives SIGINT or SIGTERM, the server then being
+// shutdown as described by Lifecycle. A nil error is returned when the server
+// is shutdown by signal or ctx.
+func (l *Lifecycle) Serve(ctx context.Context, server *http.Server, opts ...ServerOption) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg := newServerConfig(opts)
+	cfg.drain = l.drain
+	return cfg.runContext(ctx, serverListener(server))
+}
@@ -0,0 +1,126 @@
+package srv
+
+import "net/http"
+
+// methodRoute returns a Route for h at pattern qualified by method, as in
+// "GET /users", so that the mux matches the method and several methods may be
+// registered at the same path as separate routes.
+func methodRoute(method, pattern string, h any, mw []Mware) *Route {
+	return Handle(method+" "+pattern, h, mw...)
+}
+
+// Get adds a route for h at pattern that matches only GET requests, and
+// HEAD requests as do all GET patterns, wrapped with the given Mware.
+func (g *Group) Get(pattern string, h any, mw ...Mware) *Group {
+	return g.Add(methodRoute(http.MethodGet, pattern, h, mw))
+}
+
+// Post adds a route for h at pattern that matches only POST requests,
+// wrapped with the given Mware.
+func (g *Group) Post(pattern string, h any, mw ...Mware) *Group {
+	return g.Add(methodRoute(http.MethodPost, pattern, h, mw))
+}
+
+// Put adds a route for h at pattern that matches only PUT requests,
+// wrapped with the given Mware.
+func (g *Group) Put(pattern string, h any, mw ...Mware) *Group {
+	return g.Add(methodRoute(http.MethodPut, pattern, h, mw))
+}
+
+// Patch adds a route for h at pattern that matches only PATCH requests,
+// wrapped with the given Mware.
+func (g *Group) Patch(pattern string, h any, mw ...Mware) *Group {
+	return g.Add(methodRoute(http.MethodPatch, pattern, h, mw))
+}
+
+// Delete adds a route for h at pattern that matches only DELETE requests,
+// wrapped with the given Mware.
+func (g *Group) Delete(pattern string, h any, mw ...Mware) *Group {
+	return g.Add(methodRoute(http.MethodDelete, pattern, h, mw))
+}
+
+// Head adds a route for h at pattern that matches only HEAD requests,
+// wrapped with the given Mware.
+func (g *Group) Head(pattern string, h any, mw ...Mware) *Group {
+	return g.Add(methodRoute(http.MethodHead, pattern, h, mw))
+}
+
+// Options adds a route for h at pattern that matches only OPTIONS requests,
+// wrapped with the given Mware.
+func (g *Group) Options(pattern string, h any, mw ...Mware) *Group {
+	return g.Add(methodRoute(http.MethodOptions, pattern, h, mw))
+}
+
+// Get adds a route for h at pattern that matches only GET requests, and
+// HEAD requests as do all GET patterns, wrapped with the given Mware.
+func (r *Router) Get(pattern string, h any, mw ...Mware) *Router {
+	return r.Add(methodRoute(http.MethodGet, pattern, h, mw))
+}
+
+// Post adds a route for h at pattern that matches only POST requests,
+// wrapped with the given Mware.
+func (r *Router) Post(pattern string, h any, mw ...Mware) *Router {
+	return r.Add(methodRoute(http.MethodPost, pattern, h, mw))
+}
+
+// Put adds a route for h at pattern that matches only PUT requests,
+// wrapped with the given Mware.
+func (r *Router) Put(pattern string, h any, mw ...Mware) *Router {
+	return r.Add(methodRoute(http.MethodPut, pattern, h, mw))
+}
+
+// Patch adds a route for h at pattern that matches only PATCH requests,
+// wrapped with the given Mware.
+func (r *Router) Patch(pattern string, h any, mw ...Mware) *Router {
+	return r.Add(methodRoute(http.MethodPatch, pattern, h, mw))
+}
+
+// Delete adds a route for h at pattern that matches only DELETE requests,
+// wrapped with the given Mware.
+func (r *Router) Delete(pattern string, h any, mw ...Mware) *Router {
+	return r.Add(methodRoute(http.MethodDelete, pattern, h, mw))
+}
+
+// Head adds a route for h at pattern that matches only HEAD requests,
+// wrapped with the given Mware.
+func (r *Router) Head(pattern string, h any, mw ...Mware) *Router {
+	return r.Add(methodRoute(http.MethodHead, pattern, h, mw))
+}
+
+// Options adds a route for h at pattern that matches only OPTIONS requests,
+// wrapped with the given Mware.
+func (r *Router) Options(pattern string, h any, mw ...Mware) *Router {
+	return r.Add(methodRoute(http.MethodOptions, pattern, h, mw))
+}
+
+// ResourceHandlers holds the handlers of a REST resource by method, as given to
+// Resource, any left nil are not registered.
+type ResourceHandlers struct {
+	Get    http.HandlerFunc
+	Post   http.HandlerFunc
+	Put    http.HandlerFunc
+	Patch  http.HandlerFunc
+	Delete http.HandlerFunc
+}
+
+// Resource returns a Route at pattern for each of the handlers of h that is not
+// nil, each qualified by its method and wrapped with the given Mware, in the
+// order GET, POST, PUT, PATCH, DELETE.
+func Resource(pattern string, h ResourceHandlers, mw ...Mware) Routes {
+	var routes Routes
+	for _, m := range []struct {
+		method string
+		fn     http.HandlerFunc
+	}{
+		{http.MethodGet, h.Get},
+		{http.MethodPost, h.Post},
+		{http.MethodPut, h.Put},
+		{http.MethodPatch, h.Patch},
+		{http.MethodDelete, h.Delete},
+	} {
+		if m.fn != nil {
+			routes = append(routes, *methodRoute(m.method, pattern, m.fn, mw))
+		}
+	}
+	return routes
+}
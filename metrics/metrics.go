@@ -0,0 +1,93 @@
+// Package metrics provides Prometheus instrumentation for the routes of an srv
+// Router. Programs that import srv but not metrics do not link the Prometheus
+// client.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/8i8/srv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Option configures the metrics returned by New.
+type Option func(*config)
+
+type config struct {
+	registry  *prometheus.Registry
+	namespace string
+	buckets   []float64
+}
+
+// Registry sets the registry upon which the metrics are registered and from
+// which the handler serves, by default a new registry.
+func Registry(reg *prometheus.Registry) Option {
+	return func(c *config) { c.registry = reg }
+}
+
+// Namespace sets the namespace of the metrics names.
+func Namespace(ns string) Option {
+	return func(c *config) { c.namespace = ns }
+}
+
+// Buckets sets the buckets of the request duration histogram, by default
+// prometheus.DefBuckets.
+func Buckets(b ...float64) Option {
+	return func(c *config) { c.buckets = b }
+}
+
+// New returns an srv.Mware that records the count, duration and number in
+// flight of the requests that it wraps, and an http.Handler that serves the
+// metrics for use at "/metrics". Requests are labelled by method, status and
+// the pattern of the route that matched them, rather than their path, so as
+// to keep the cardinality of the labels bounded.
+func New(opts ...Option) (srv.Mware, http.Handler) {
+	cfg := config{buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.registry == nil {
+		cfg.registry = prometheus.NewRegistry()
+	}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: cfg.namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests.",
+	}, []string{"method", "pattern", "status"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: cfg.namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests.",
+		Buckets:   cfg.buckets,
+	}, []string{"method", "pattern", "status"})
+	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: cfg.namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests being served.",
+	}, []string{"method", "pattern"})
+	cfg.registry.MustRegister(requests, duration, inFlight)
+
+	mw := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			pattern := srv.Pattern(req)
+			if pattern == "" {
+				pattern = "unmatched"
+			}
+			gauge := inFlight.WithLabelValues(req.Method, pattern)
+			gauge.Inc()
+			defer gauge.Dec()
+			start := time.Now()
+			w := srv.NewResponseWriter(res)
+			next(w, req)
+			status := strconv.Itoa(w.Status())
+			requests.WithLabelValues(req.Method, pattern, status).Inc()
+			duration.WithLabelValues(req.Method, pattern, status).
+				Observe(time.Since(start).Seconds())
+		}
+	}
+	handler := promhttp.HandlerFor(cfg.registry, promhttp.HandlerOpts{})
+	return mw, handler
+}
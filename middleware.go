@@ -1,19 +1,696 @@
 package srv
 
-import "net/http"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
 
-// Redirect routes any http requests to an https equivalent.
+// Redirect routes any http requests to an https equivalent, requests made to
+// the port HTTP are redirected to the port HTTPS.
 func Redirect(HTTP, HTTPS string) http.HandlerFunc {
+	return RedirectWith(RedirectConfig{FromPort: HTTP, Port: HTTPS})
+}
+
+// RedirectConfig configures RedirectWith.
+type RedirectConfig struct {
+	// Port is the port to which requests are redirected, ":8443", it
+	// replaces any port given in the request host.
+	Port string
+	// FromPort when set restricts the replacement of the port to requests
+	// made to this port, requests on other ports keep their port.
+	FromPort string
+	// StripPort removes the port from the request host when Port is not
+	// set, redirecting to the default https port.
+	StripPort bool
+	// Status is the redirect status code, defaulting to 307 Temporary
+	// Redirect.
+	Status int
+}
+
+// RedirectWith routes any http requests to an https equivalent as configured
+// by cfg.
+func RedirectWith(cfg RedirectConfig) http.HandlerFunc {
+	status := cfg.Status
+	if status == 0 {
+		status = http.StatusTemporaryRedirect
+	}
+	port := strings.TrimPrefix(cfg.Port, ":")
+	from := strings.TrimPrefix(cfg.FromPort, ":")
 	return func(res http.ResponseWriter, req *http.Request) {
-		if req.Host == "localhost"+HTTP {
-			req.Host = "localhost" + HTTPS
+		// The client has gone away, there is no one to redirect.
+		if req.Context().Err() != nil {
+			return
+		}
+		host := req.Host
+		if h, p, err := net.SplitHostPort(req.Host); err == nil {
+			switch {
+			case port != "" && (from == "" || from == p):
+				host = net.JoinHostPort(h, port)
+			case port == "" && cfg.StripPort:
+				host = h
+				if strings.Contains(h, ":") {
+					host = "[" + h + "]"
+				}
+			}
+		} else if port != "" && from == "" {
+			host = net.JoinHostPort(strings.Trim(req.Host, "[]"), port)
 		}
 		// Reconstruct the path with a TLS base.
-		target := "https://" + req.Host + req.URL.Path
+		target := "https://" + host + req.URL.Path
 		// Add querys if present.
 		if len(req.URL.RawQuery) > 0 {
 			target += "?" + req.URL.RawQuery
 		}
-		http.Redirect(res, req, target, http.StatusTemporaryRedirect)
+		http.Redirect(res, req, target, status)
+	}
+}
+
+// RecoverOption configures the Recover Mware.
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	handler func(http.ResponseWriter, *http.Request, any)
+}
+
+// RecoverHandler sets the function that is called with the recovered value in
+// place of the default, which logs the value and stack and then responds with a
+// 500 Internal Server Error.
+func RecoverHandler(fn func(http.ResponseWriter, *http.Request, any)) RecoverOption {
+	return func(c *recoverConfig) {
+		c.handler = fn
+	}
+}
+
+// Recover returns an Mware that recovers from any panic raised by the handlers
+// that it wraps, http.ErrAbortHandler is re-panicked so as to preserve the
+// standard libraries abort semantics.
+func Recover(opts ...RecoverOption) Mware {
+	cfg := recoverConfig{handler: recovered}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				cfg.handler(res, req, v)
+			}()
+			next(res, req)
+		}
+	}
+}
+
+// recovered is the default Recover handler.
+func recovered(res http.ResponseWriter, req *http.Request, v any) {
+	logger().Printf("%s: panic serving %s %s: %v\n%s",
+		pkg, req.Method, req.URL.Path, v, debug.Stack())
+	http.Error(res, http.StatusText(http.StatusInternalServerError),
+		http.StatusInternalServerError)
+}
+
+// Logger returns an Mware that logs the method, path, status code, response
+// size and duration of every request to l, or to the standard logger if l is
+// nil. When wrapped by RequestID the request ID is also logged, requests
+// aborted with Abort are marked as such.
+func Logger(l *log.Logger) Mware {
+	if l == nil {
+		l = log.Default()
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			rec := NewResponseWriter(res)
+			next(rec, req)
+			id := RequestIDFrom(req.Context())
+			if id != "" {
+				id = " " + id
+			}
+			if rec.aborted {
+				id += " aborted"
+			}
+			method := req.Method
+			if head, _ := req.Context().Value(headKey{}).(bool); head {
+				method = http.MethodHead
+			}
+			l.Printf("%s %s %d %d %s%s", method, req.URL.Path,
+				rec.Status(), rec.BytesWritten(), time.Since(start), id)
+		}
+	}
+}
+
+type loggerKey struct{}
+
+// WithLogger returns an Mware that stores a logger in the request context for
+// handlers to retrieve with LoggerFrom. When wrapped by RequestID the logger
+// is derived from l with the request ID appended to its prefix, else l itself
+// is stored. If l is nil the standard logger is used.
+func WithLogger(l *log.Logger) Mware {
+	if l == nil {
+		l = log.Default()
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			rl := l
+			if id := RequestIDFrom(req.Context()); id != "" {
+				rl = log.New(l.Writer(), l.Prefix()+id+" ", l.Flags())
+			}
+			ctx := context.WithValue(req.Context(), loggerKey{}, rl)
+			next(res, req.WithContext(ctx))
+		}
+	}
+}
+
+// LoggerFrom returns the logger stored in ctx by the WithLogger Mware, or the
+// standard logger if there is none, it never returns nil.
+func LoggerFrom(ctx context.Context) *log.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*log.Logger); ok {
+		return l
+	}
+	return log.Default()
+}
+
+// Timeout returns an Mware that gives the handlers it wraps d in which to
+// respond, thereafter the request context is cancelled and the client is sent
+// a 503 Service Unavailable, even if the handler ignores the cancellation and
+// continues to run, as with http.TimeoutHandler upon which it is built.
+//
+// The http.ResponseWriter given to the handler does not implement http.Flusher
+// or http.Hijacker, the response being buffered until the handler returns, so
+// streaming handlers and those that hijack the connection should not be
+// wrapped by Timeout.
+func Timeout(d time.Duration) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return http.TimeoutHandler(next, d, "").ServeHTTP
+	}
+}
+
+// SecureConfig configures the SecureHeaders Mware, any field left empty
+// results in its header being omitted.
+type SecureConfig struct {
+	// StrictTransportSecurity, "max-age=63072000; includeSubDomains".
+	StrictTransportSecurity string
+	// ContentTypeOptions, "nosniff".
+	ContentTypeOptions string
+	// FrameOptions, "DENY" or "SAMEORIGIN".
+	FrameOptions string
+	// ReferrerPolicy, "strict-origin-when-cross-origin".
+	ReferrerPolicy string
+	// ContentSecurityPolicy, "default-src 'self'".
+	ContentSecurityPolicy string
+}
+
+// DefaultSecureConfig returns a SecureConfig with sane defaults for each
+// header.
+func DefaultSecureConfig() SecureConfig {
+	return SecureConfig{
+		StrictTransportSecurity: "max-age=63072000; includeSubDomains",
+		ContentTypeOptions:      "nosniff",
+		FrameOptions:            "DENY",
+		ReferrerPolicy:          "strict-origin-when-cross-origin",
+		ContentSecurityPolicy:   "default-src 'self'",
+	}
+}
+
+// SecureHeaders returns an Mware that sets the hardening headers configured in
+// cfg on every response.
+func SecureHeaders(cfg SecureConfig) Mware {
+	headers := [][2]string{
+		{"Strict-Transport-Security", cfg.StrictTransportSecurity},
+		{"X-Content-Type-Options", cfg.ContentTypeOptions},
+		{"X-Frame-Options", cfg.FrameOptions},
+		{"Referrer-Policy", cfg.ReferrerPolicy},
+		{"Content-Security-Policy", cfg.ContentSecurityPolicy},
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			h := res.Header()
+			for _, kv := range headers {
+				if kv[1] != "" {
+					h.Set(kv[0], kv[1])
+				}
+			}
+			next(res, req)
+		}
+	}
+}
+
+// HeadersOption configures the Headers Mware.
+type HeadersOption func(*headersConfig)
+
+type headersConfig struct {
+	override bool
+}
+
+// HeadersOverride has the headers of Headers replace those of the same name set
+// by the handler, rather than the handlers taking precedence.
+func HeadersOverride() HeadersOption {
+	return func(c *headersConfig) { c.override = true }
+}
+
+// Headers returns an Mware that sets the headers h on every response, such as
+// Server or a build version. The headers are set before the handler is called
+// so that by default any that the handler itself sets take precedence, with
+// HeadersOverride they are instead set again as the header is written.
+func Headers(h map[string]string, opts ...HeadersOption) Mware {
+	var cfg headersConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	headers := make(http.Header, len(h))
+	for k, v := range h {
+		headers.Set(k, v)
+	}
+	set := func(dst http.Header) {
+		for k, v := range headers {
+			dst[k] = append([]string(nil), v...)
+		}
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			set(res.Header())
+			if !cfg.override {
+				next(res, req)
+				return
+			}
+			next(&headersWriter{ResponseWriter: newResponseWriter(res),
+				set: set}, req)
+		}
+	}
+}
+
+// CacheControl returns an Mware that sets the Cache-Control header of every
+// response to value, such as "no-store" or "public, max-age=31536000,
+// immutable", unless the handler sets its own, which with HeadersOverride is
+// instead replaced.
+func CacheControl(value string, opts ...HeadersOption) Mware {
+	return Headers(map[string]string{"Cache-Control": value}, opts...)
+}
+
+// headersWriter sets its headers on the response once more as the header is
+// written, replacing any of the same name set by the handler.
+type headersWriter struct {
+	*ResponseWriter
+	set  func(http.Header)
+	done bool
+}
+
+func (w *headersWriter) apply() {
+	if !w.done {
+		w.done = true
+		w.set(w.Header())
+	}
+}
+
+func (w *headersWriter) WriteHeader(code int) {
+	if code >= 200 {
+		w.apply()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headersWriter) Write(b []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headersWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *headersWriter) FlushError() error {
+	w.apply()
+	return w.ResponseWriter.FlushError()
+}
+
+// Consumes returns an Mware that responds 415 Unsupported Media Type to
+// requests with a body whose Content-Type is not one of the given media types,
+// any parameters such as charset being ignored. Requests without a body are
+// passed through.
+func Consumes(types ...string) Mware {
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[strings.ToLower(t)] = true
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			if req.ContentLength == 0 || req.Body == nil || req.Body == http.NoBody {
+				next(res, req)
+				return
+			}
+			mt, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+			if err != nil || !allowed[mt] {
+				http.Error(res, http.StatusText(http.StatusUnsupportedMediaType),
+					http.StatusUnsupportedMediaType)
+				return
+			}
+			next(res, req)
+		}
+	}
+}
+
+// When returns an Mware that applies mw only to requests for which pred
+// returns true, other requests being passed directly to the next handler.
+func When(pred func(*http.Request) bool, mw Mware) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		wrapped := mw(next)
+		return func(res http.ResponseWriter, req *http.Request) {
+			if pred(req) {
+				wrapped(res, req)
+				return
+			}
+			next(res, req)
+		}
+	}
+}
+
+// Unless returns an Mware that applies mw only to requests for which pred
+// returns false.
+func Unless(pred func(*http.Request) bool, mw Mware) Mware {
+	return When(func(req *http.Request) bool { return !pred(req) }, mw)
+}
+
+// DefaultMaxBody is a sensible limit for MaxBodyBytes, 1MB.
+const DefaultMaxBody = 1 << 20
+
+// MaxBodyBytes returns an Mware that limits request bodies to n bytes, those
+// that declare a larger Content-Length are rejected with a 413 Request Entity
+// Too Large before the handler is called, otherwise reads beyond the limit
+// fail and the response status is set to 413 regardless of that written by
+// the handler.
+func MaxBodyBytes(n int64) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			if req.ContentLength > n {
+				http.Error(res, http.StatusText(http.StatusRequestEntityTooLarge),
+					http.StatusRequestEntityTooLarge)
+				return
+			}
+			body := &maxBody{ReadCloser: http.MaxBytesReader(res, req.Body, n)}
+			req.Body = body
+			w := &maxBodyWriter{ResponseWriter: newResponseWriter(res), body: body}
+			next(w, req)
+			if body.exceeded && w.status == 0 {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge),
+					http.StatusRequestEntityTooLarge)
+			}
+		}
+	}
+}
+
+// maxBody records whether reading the request body exceeded its limit.
+type maxBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *maxBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// maxBodyWriter replaces the status of the response with a 413 once the
+// request body has exceeded its limit.
+type maxBodyWriter struct {
+	*ResponseWriter
+	body *maxBody
+}
+
+func (w *maxBodyWriter) WriteHeader(code int) {
+	if w.body.exceeded {
+		code = http.StatusRequestEntityTooLarge
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *maxBodyWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// OverrideOption configures the MethodOverride Mware.
+type OverrideOption func(*overrideConfig)
+
+type overrideConfig struct {
+	header  string
+	field   string
+	methods []string
+}
+
+// OverrideHeader sets the header from which the method is read,
+// X-HTTP-Method-Override by default.
+func OverrideHeader(name string) OverrideOption {
+	return func(c *overrideConfig) { c.header = name }
+}
+
+// OverrideField sets the form field from which the method is read, _method by
+// default.
+func OverrideField(name string) OverrideOption {
+	return func(c *overrideConfig) { c.field = name }
+}
+
+// OverrideMethods sets the methods to which a request may be overridden, PUT,
+// PATCH and DELETE by default.
+func OverrideMethods(methods ...string) OverrideOption {
+	return func(c *overrideConfig) { c.methods = methods }
+}
+
+// MethodOverride returns an Mware that replaces the method of POST requests
+// with that given in the override header or, for form submissions, the
+// override form field, so long as it is one of the permitted methods.
+//
+// As it modifies the method, MethodOverride must run before anything that
+// depends upon it, such as Route.Method. Method qualified patterns, "PUT /x",
+// are matched by the mux before any Mware is run, to override the method for
+// these wrap the composed mux itself, MethodOverride()(mux.ServeHTTP).
+func MethodOverride(opts ...OverrideOption) Mware {
+	cfg := overrideConfig{
+		header:  "X-HTTP-Method-Override",
+		field:   "_method",
+		methods: []string{http.MethodPut, http.MethodPatch, http.MethodDelete},
 	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodPost {
+				next(res, req)
+				return
+			}
+			m := req.Header.Get(cfg.header)
+			if m == "" {
+				ct := req.Header.Get("Content-Type")
+				if strings.HasPrefix(ct, "application/x-www-form-urlencoded") ||
+					strings.HasPrefix(ct, "multipart/form-data") {
+					m = req.PostFormValue(cfg.field)
+				}
+			}
+			m = strings.ToUpper(m)
+			for _, allowed := range cfg.methods {
+				if m == strings.ToUpper(allowed) {
+					req.Method = m
+					break
+				}
+			}
+			next(res, req)
+		}
+	}
+}
+
+// WithValue returns an Mware that stores val under key in the request context.
+// To avoid collisions with values stored by other packages key should be of
+// an unexported type defined by the caller, not a string or other built in
+// type, as in:
+//
+//	type tenantKey struct{}
+//
+//	g.Wrap(srv.WithValue(tenantKey{}, "acme"))
+//	...
+//	tenant, _ := req.Context().Value(tenantKey{}).(string)
+func WithValue(key, val any) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			ctx := context.WithValue(req.Context(), key, val)
+			next(res, req.WithContext(ctx))
+		}
+	}
+}
+
+// Chain combines the given Mware into one, applying them exactly as would
+// passing them individually to Wrap, such that Wrap(Chain(a, b, c)) is
+// equivalent to Wrap(a, b, c).
+func Chain(mw ...Mware) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		for _, fn := range mw {
+			next = fn(next)
+		}
+		return next
+	}
+}
+
+// CleanOption configures the CleanPath Mware.
+type CleanOption func(*cleanConfig)
+
+type cleanConfig struct {
+	lower bool
+}
+
+// LowerCase additionally lowercases the request path.
+func LowerCase() CleanOption {
+	return func(c *cleanConfig) { c.lower = true }
+}
+
+// CleanPath returns an Mware that normalises the request path, collapsing
+// repeated slashes and resolving "." and ".." elements whilst keeping any
+// trailing slash. GET and HEAD requests for a path that is not clean are
+// redirected to the clean path with a 301 Moved Permanently, the query being
+// preserved, other requests have their path replaced. The path is cleaned in
+// its escaped form, an escaped slash, %2F, not separating elements.
+//
+// The mux matches a request before any Mware registered upon the Router is
+// run, so to take effect before routing CleanPath must be the outermost
+// handler, wrapping the composed mux itself, CleanPath()(mux.ServeHTTP).
+func CleanPath(opts ...CleanOption) Mware {
+	var cfg cleanConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			// The escaped path is cleaned, such that escaped slashes and
+			// question marks remain within their segments, and the
+			// cleaned path, being clean, is not redirected again.
+			escaped := req.URL.EscapedPath()
+			p := cleanPath(escaped)
+			if cfg.lower {
+				p = strings.ToLower(p)
+			}
+			if p == escaped {
+				next(res, req)
+				return
+			}
+			if req.Method == http.MethodGet || req.Method == http.MethodHead {
+				// The client has gone away, there is no one to redirect.
+				if req.Context().Err() != nil {
+					return
+				}
+				target := p
+				if req.URL.RawQuery != "" {
+					target += "?" + req.URL.RawQuery
+				}
+				http.Redirect(res, req, target, http.StatusMovedPermanently)
+				return
+			}
+			if path, err := url.PathUnescape(p); err == nil {
+				req.URL.Path, req.URL.RawPath = path, p
+			}
+			next(res, req)
+		}
+	}
+}
+
+// cleanPath returns the canonical form of p, as path.Clean but keeping any
+// trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
+
+// Maintenance returns an Mware that, whilst enabled is set, responds to every
+// request with a 503 Service Unavailable and a Retry-After header, save those
+// whose path, or the pattern of the route that matched them, is given in
+// allow, so that health checks and ops tooling remain reachable. The flag may
+// be flipped at any time.
+func Maintenance(enabled *atomic.Bool, retryAfter time.Duration, allow ...string) Mware {
+	allowed := make(map[string]bool, len(allow))
+	for _, p := range allow {
+		allowed[p] = true
+	}
+	secs := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			if !enabled.Load() || allowed[req.URL.Path] || allowed[Pattern(req)] {
+				next(res, req)
+				return
+			}
+			res.Header().Set("Retry-After", secs)
+			http.Error(res, http.StatusText(http.StatusServiceUnavailable),
+				http.StatusServiceUnavailable)
+		}
+	}
+}
+
+type bodyKey struct{}
+
+// BufferBody returns an Mware that reads the whole request body into memory,
+// responding with a 413 Request Entity Too Large should it exceed maxBytes.
+// The body is replaced by a reader over the buffer, req.GetBody is set to
+// return a fresh reader and the buffer itself is made available by
+// BufferedBody, so that several middleware and the handler may each read it.
+func BufferBody(maxBytes int64) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			b, err := io.ReadAll(io.LimitReader(req.Body, maxBytes+1))
+			req.Body.Close()
+			if err != nil {
+				http.Error(res, http.StatusText(http.StatusBadRequest),
+					http.StatusBadRequest)
+				return
+			}
+			if int64(len(b)) > maxBytes {
+				http.Error(res, http.StatusText(http.StatusRequestEntityTooLarge),
+					http.StatusRequestEntityTooLarge)
+				return
+			}
+			ctx := context.WithValue(req.Context(), bodyKey{}, b)
+			req = req.WithContext(ctx)
+			req.Body = io.NopCloser(bytes.NewReader(b))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(b)), nil
+			}
+			next(res, req)
+		}
+	}
+}
+
+// BufferedBody returns the request body buffered by BufferBody, reporting
+// whether there is one. The returned slice must not be modified.
+func BufferedBody(req *http.Request) ([]byte, bool) {
+	b, ok := req.Context().Value(bodyKey{}).([]byte)
+	return b, ok
 }
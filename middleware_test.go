@@ -0,0 +1,219 @@
+package srv
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stack returns the package's middleware that wrap the ResponseWriter, in the
+// order that an application would typically use them, logging to logs.
+func stack(logs io.Writer) []Mware {
+	return []Mware{
+		Recover(),
+		Logger(log.New(logs, "", 0)),
+		RequestID(),
+		SecureHeaders(DefaultSecureConfig()),
+		Session(NewMemoryStore(time.Minute)),
+		MaxBodyBytes(1 << 20),
+		CacheControl("no-store"),
+		ETag(),
+		Compress(),
+	}
+}
+
+func TestChainMatchesWrap(t *testing.T) {
+	var calls []string
+	mw := []Mware{trace(&calls, "a"), trace(&calls, "b"), trace(&calls, "c")}
+	h := func(http.ResponseWriter, *http.Request) { calls = append(calls, "h") }
+	order := func(r *Router) []string {
+		calls = nil
+		TestRouter(r, httptest.NewRequest("GET", "/", nil))
+		return calls
+	}
+	tests := []struct {
+		name        string
+		wrap, chain *Router
+	}{
+		{"Route",
+			NewRouter().Add(Handle("/", h).Wrap(mw...)),
+			NewRouter().Add(Handle("/", h).Wrap(Chain(mw...)))},
+		{"Group",
+			NewRouter().Add((&Group{}).Wrap(mw...).Add(Handle("/", h))),
+			NewRouter().Add((&Group{}).Wrap(Chain(mw...)).Add(Handle("/", h)))},
+		{"Router",
+			NewRouter().Wrap(mw...).Add(Handle("/", h)),
+			NewRouter().Wrap(Chain(mw...)).Add(Handle("/", h))},
+	}
+	for _, tt := range tests {
+		want := order(tt.wrap)
+		if len(want) != 7 {
+			t.Fatalf("%s: Wrap ran %v", tt.name, want)
+		}
+		checkCalls(t, order(tt.chain), want...)
+	}
+}
+
+func TestHijackBehindStack(t *testing.T) {
+	var logs strings.Builder
+	r := NewRouter().Use(stack(&logs)...).Add(Handle("/ws", func(res http.ResponseWriter, req *http.Request) {
+		conn, rw, err := http.NewResponseController(res).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		line, _ := rw.ReadString('\n')
+		rw.WriteString(line)
+		rw.Flush()
+	}))
+	ts := NewTestServer(r)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: test\r\n"+
+		"Accept-Encoding: gzip\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d want 101", res.StatusCode)
+	}
+	io.WriteString(conn, "ping\n")
+	if line, err := br.ReadString('\n'); err != nil || line != "ping\n" {
+		t.Errorf("echo: got %q %v", line, err)
+	}
+}
+
+func TestFlushBehindStack(t *testing.T) {
+	var logs strings.Builder
+	next := make(chan struct{})
+	r := NewRouter().Use(stack(&logs)...).Add(Handle("/events", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/event-stream")
+		rc := http.NewResponseController(res)
+		for i := 0; i < 2; i++ {
+			io.WriteString(res, "data: event\n\n")
+			if err := rc.Flush(); err != nil {
+				t.Error(err)
+				return
+			}
+			<-next
+		}
+	}))
+	ts := NewTestServer(r)
+	defer ts.Close()
+	defer close(next)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	client := &http.Client{Timeout: 5 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("response not compressed: %v", res.Header)
+	}
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(zr)
+	for i := 0; i < 2; i++ {
+		// Each event must arrive before the handler is allowed to continue.
+		line, err := br.ReadString('\n')
+		if err != nil || line != "data: event\n" {
+			t.Fatalf("event %d: got %q %v", i, line, err)
+		}
+		br.ReadString('\n')
+		next <- struct{}{}
+	}
+}
+
+func TestRedirectCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slash, err := NewRouter().RedirectTrailingSlash(SlashStrip).Add(Handle("/x", http.NotFound)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name, path string
+		serve      http.HandlerFunc
+	}{
+		{"RedirectWith", "/x/", RedirectWith(RedirectConfig{Port: ":8443"})},
+		{"trailing slash", "/x/", slash.ServeHTTP},
+		{"CleanPath", "/x//y", CleanPath()(http.NotFound)},
+	} {
+		req := httptest.NewRequest("GET", "http://example.com:8080"+tt.path, nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		tt.serve(rec, req)
+		if len(rec.Header()) != 0 || rec.Body.Len() != 0 || rec.Code != http.StatusOK {
+			t.Errorf("%s: wrote status %d headers %v body %q", tt.name, rec.Code, rec.Header(), rec.Body)
+		}
+
+		req = req.WithContext(context.Background())
+		rec = httptest.NewRecorder()
+		tt.serve(rec, req)
+		if rec.Header().Get("Location") == "" {
+			t.Errorf("%s: live request not redirected", tt.name)
+		}
+	}
+}
+
+func TestCleanPathEscaped(t *testing.T) {
+	var got string
+	h := CleanPath()(func(res http.ResponseWriter, req *http.Request) {
+		got = req.URL.EscapedPath()
+	})
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{"GET", "/a//b%3Fc?q=1", "/a/b%3Fc?q=1"},
+		{"GET", "/a/./b%2Fc/", "/a/b%2Fc/"},
+		{"GET", "/a/../b%20c", "/b%20c"},
+		{"POST", "/a//b%3Fc", "/a/b%3Fc"},
+	}
+	for _, tt := range tests {
+		got = ""
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if tt.method == "POST" {
+			if got != tt.want {
+				t.Errorf("%s %s: got path %q want %q", tt.method, tt.path, got, tt.want)
+			}
+			continue
+		}
+		loc := rec.Header().Get("Location")
+		if rec.Code != http.StatusMovedPermanently || loc != tt.want {
+			t.Errorf("%s: got %d to %q want %q", tt.path, rec.Code, loc, tt.want)
+			continue
+		}
+		// The target is clean and so is served, not redirected again.
+		rec = httptest.NewRecorder()
+		h(rec, httptest.NewRequest("GET", loc, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: target %q redirected again to %q", tt.path, loc, rec.Header().Get("Location"))
+		}
+	}
+}
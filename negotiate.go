@@ -0,0 +1,102 @@
+package srv
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a single media range of an Accept header.
+type mediaRange struct {
+	typ, sub string
+	q        float64
+}
+
+// parseAccept parses the media ranges of an Accept header, ignoring any that
+// are malformed.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mt, params, _ := strings.Cut(part, ";")
+		typ, sub, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mt)), "/")
+		if !ok || typ == "" || sub == "" || (typ == "*" && sub != "*") {
+			continue
+		}
+		r := mediaRange{typ: typ, sub: sub, q: 1}
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(k) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil &&
+				q >= 0 && q <= 1 {
+				r.q = q
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// quality returns the q value given to the media type mt by the most specific
+// of ranges that matches it, or -1 if none match.
+func quality(ranges []mediaRange, mt string) float64 {
+	typ, sub, _ := strings.Cut(strings.ToLower(mt), "/")
+	q, specificity := -1.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.sub == sub:
+			s = 2
+		case r.typ == typ && r.sub == "*":
+			s = 1
+		case r.typ == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// Negotiate returns a function that selects from offered the media type most
+// preferred by the requests Accept header, according to its q values and
+// wildcards, ties being resolved by the order of offered. An empty string is
+// returned when none are acceptable, a request without an Accept header
+// accepts the first offered.
+func Negotiate(offered ...string) func(*http.Request) string {
+	return func(req *http.Request) string {
+		header := req.Header.Get("Accept")
+		if header == "" {
+			if len(offered) == 0 {
+				return ""
+			}
+			return offered[0]
+		}
+		ranges := parseAccept(header)
+		best, bestq := "", 0.0
+		for _, mt := range offered {
+			if q := quality(ranges, mt); q > bestq {
+				best, bestq = mt, q
+			}
+		}
+		return best
+	}
+}
+
+// Acceptable returns an Mware that responds with a 406 Not Acceptable to
+// requests that accept none of the offered media types.
+func Acceptable(offered ...string) Mware {
+	negotiate := Negotiate(offered...)
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			if negotiate(req) == "" {
+				http.Error(res, http.StatusText(http.StatusNotAcceptable),
+					http.StatusNotAcceptable)
+				return
+			}
+			next(res, req)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package srv
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// ProxyOption configures the Route returned by Proxy.
+type ProxyOption func(*proxyConfig)
+
+type proxyConfig struct {
+	strip    bool
+	headers  http.Header
+	director func(*http.Request)
+	modify   func(*http.Response) error
+}
+
+// StripPrefix removes the path of the routes pattern, including any prefix
+// given by a Group or Mount, from the request before it is forwarded upstream.
+func StripPrefix() ProxyOption {
+	return func(c *proxyConfig) { c.strip = true }
+}
+
+// ProxyHeader sets a header on every request forwarded upstream.
+func ProxyHeader(key, value string) ProxyOption {
+	return func(c *proxyConfig) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Set(key, value)
+	}
+}
+
+// ProxyDirector replaces the proxies Director, the function that rewrites the
+// request before it is forwarded.
+func ProxyDirector(fn func(*http.Request)) ProxyOption {
+	return func(c *proxyConfig) { c.director = fn }
+}
+
+// ProxyModifyResponse sets the proxies ModifyResponse function.
+func ProxyModifyResponse(fn func(*http.Response) error) ProxyOption {
+	return func(c *proxyConfig) { c.modify = fn }
+}
+
+// Proxy returns a Route that forwards requests made to pattern to targetURL
+// using an httputil.ReverseProxy, an error is returned if targetURL is not a
+// valid absolute URL.
+func Proxy(pattern, targetURL string, opts ...ProxyOption) (*Route, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: proxy target: %w", pkg, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("%s: proxy target %q is not an absolute URL",
+			pkg, targetURL)
+	}
+	var cfg proxyConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	if cfg.director != nil {
+		proxy.Director = cfg.director
+	}
+	if cfg.headers != nil {
+		director := proxy.Director
+		proxy.Director = func(req *http.Request) {
+			director(req)
+			for k, v := range cfg.headers {
+				req.Header[k] = v
+			}
+		}
+	}
+	proxy.ModifyResponse = cfg.modify
+	fn := proxy.ServeHTTP
+	if cfg.strip {
+		fn = stripPattern(pattern, proxy)
+	}
+	return &Route{pattern: pattern, fn: fn}, nil
+}
@@ -0,0 +1,31 @@
+package srv
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyStripPrefixed(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(req.URL.Path))
+	}))
+	defer upstream.Close()
+	route := func() *Route {
+		r, err := Proxy("/svc/", upstream.URL, StripPrefix())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	r := NewRouter().
+		Add(route()).
+		Add((&Group{}).Prefix("/api").Add(route())).
+		Mount("/v1", NewRouter().Add(route()))
+	for _, path := range []string{"/svc/a/b", "/api/svc/a/b", "/v1/svc/a/b"} {
+		rec := TestRouter(r, httptest.NewRequest("GET", path, nil))
+		if got := rec.Body.String(); got != "/a/b" {
+			t.Errorf("%s: forwarded %q want %q", path, got, "/a/b")
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package srv
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig configures the RateLimit Mware.
+type RateLimitConfig struct {
+	// Rate is the number of requests per second permitted for each key.
+	Rate float64
+	// Burst is the number of requests that may be made at once, at least 1.
+	Burst int
+	// Key returns the key by which requests are limited, by default the
+	// host of the requests RemoteAddr.
+	Key func(*http.Request) string
+	// TTL is the duration after which the bucket of an idle key is
+	// discarded, 10 minutes by default.
+	TTL time.Duration
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter holds a token bucket per key.
+type limiter struct {
+	mu      sync.Mutex
+	cfg     RateLimitConfig
+	buckets map[string]*bucket
+	sweep   time.Time
+}
+
+// allow takes a token from the bucket of key, if there are none it returns
+// the duration until one is available.
+func (l *limiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.sweep) > l.cfg.TTL {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > l.cfg.TTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.sweep = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.cfg.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.cfg.Burst),
+		b.tokens+now.Sub(b.last).Seconds()*l.cfg.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if l.cfg.Rate <= 0 {
+		return false, l.cfg.TTL
+	}
+	wait := (1 - b.tokens) / l.cfg.Rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// RateLimit returns an Mware that limits the rate of requests made by each
+// client using a token bucket per key, requests exceeding the limit receive a
+// 429 Too Many Requests with a Retry-After header. The buckets of keys that
+// have been idle for longer than the configured TTL are discarded.
+func RateLimit(cfg RateLimitConfig) Mware {
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	if cfg.Key == nil {
+		cfg.Key = remoteHost
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Minute
+	}
+	l := &limiter{cfg: cfg, buckets: make(map[string]*bucket)}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			ok, wait := l.allow(cfg.Key(req), time.Now())
+			if !ok {
+				secs := int(math.Ceil(wait.Seconds()))
+				res.Header().Set("Retry-After", strconv.Itoa(secs))
+				http.Error(res, http.StatusText(http.StatusTooManyRequests),
+					http.StatusTooManyRequests)
+				return
+			}
+			next(res, req)
+		}
+	}
+}
+
+// remoteHost returns the host of the requests RemoteAddr.
+func remoteHost(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
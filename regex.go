@@ -0,0 +1,73 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+type regexKey struct{}
+
+// RegexRoute returns a Route registered at pattern, as with Handle, that
+// further matches the whole path of each request against the regular expression
+// expr, responding 404 Not Found to requests that do not match. The named
+// capture groups of expr are stored in the request context to be read by
+// RegexParam. As the path includes any prefix given by the Groups of the Route
+// expr must allow for it, pattern is typically a subtree such as "/files/" in
+// which the expression then matches, "/files/(?P<name>[a-z]+)\.txt".
+func RegexRoute(pattern, expr string, h any, mw ...Mware) *Route {
+	route, err := regexRoute(pattern, expr, h, mw...)
+	if err != nil {
+		logger().Output(2, err.Error())
+		os.Exit(1)
+	}
+	return route
+}
+
+// RegexRouteE is as RegexRoute but returns an error rather than exiting when
+// expr does not compile or h is not a supported handler, the pattern is also
+// checked with ValidatePattern.
+func RegexRouteE(pattern, expr string, h any, mw ...Mware) (*Route, error) {
+	if err := ValidatePattern(pattern); err != nil {
+		return nil, err
+	}
+	return regexRoute(pattern, expr, h, mw...)
+}
+
+func regexRoute(pattern, expr string, h any, mw ...Mware) (*Route, error) {
+	if _, err := regexp.Compile(expr); err != nil {
+		return nil, fmt.Errorf("%s: %q: %w", pkg, pattern, err)
+	}
+	re := regexp.MustCompile("^(?:" + expr + ")$")
+	route, err := handle(pattern, h)
+	if err != nil {
+		return nil, err
+	}
+	next := route.fn
+	names := re.SubexpNames()
+	route.fn = func(res http.ResponseWriter, req *http.Request) {
+		m := re.FindStringSubmatch(req.URL.Path)
+		if m == nil {
+			http.NotFound(res, req)
+			return
+		}
+		params := make(map[string]string, len(names))
+		for i, name := range names {
+			if name != "" {
+				params[name] = m[i]
+			}
+		}
+		ctx := context.WithValue(req.Context(), regexKey{}, params)
+		next(res, req.WithContext(ctx))
+	}
+	return route.Wrap(mw...), nil
+}
+
+// RegexParam returns the value of the named capture group matched by the
+// RegexRoute that served req, or an empty string if there is none.
+func RegexParam(req *http.Request, name string) string {
+	params, _ := req.Context().Value(regexKey{}).(map[string]string)
+	return params[name]
+}
@@ -0,0 +1,79 @@
+package srv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDOption configures the RequestID Mware.
+type RequestIDOption func(*requestIDConfig)
+
+type requestIDConfig struct {
+	header   string
+	generate func() string
+}
+
+// RequestIDHeader sets the header from which an incoming request ID is read
+// and in which the ID is returned, X-Request-ID by default.
+func RequestIDHeader(name string) RequestIDOption {
+	return func(c *requestIDConfig) { c.header = name }
+}
+
+// RequestIDGenerator sets the function used to generate new request IDs, by
+// default 16 random bytes hex encoded.
+func RequestIDGenerator(fn func() string) RequestIDOption {
+	return func(c *requestIDConfig) { c.generate = fn }
+}
+
+type requestIDKey struct{}
+
+// RequestID returns an Mware that gives every request an ID, reusing that of
+// the incoming request header when present and valid, the ID is stored in the
+// request context and set in the response header.
+func RequestID(opts ...RequestIDOption) Mware {
+	cfg := requestIDConfig{header: "X-Request-ID", generate: newRequestID}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(cfg.header)
+			if !validRequestID(id) {
+				id = cfg.generate()
+			}
+			res.Header().Set(cfg.header, id)
+			ctx := context.WithValue(req.Context(), requestIDKey{}, id)
+			next(res, req.WithContext(ctx))
+		}
+	}
+}
+
+// RequestIDFrom returns the request ID stored in ctx by the RequestID Mware,
+// or an empty string if there is none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns 16 random bytes hex encoded.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether an incoming request ID is of reasonable
+// length and contains only printable ASCII, so that it is safe to log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
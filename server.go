@@ -0,0 +1,207 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ServerOption configures the http.Server built by ListenAndServe and
+// Router.Server.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	read       time.Duration
+	readHeader time.Duration
+	write      time.Duration
+	idle       time.Duration
+	maxHeader  int
+	shutdown   time.Duration
+	drain      func()
+}
+
+func newServerConfig(opts []ServerOption) serverConfig {
+	cfg := serverConfig{
+		read:       15 * time.Second,
+		readHeader: 5 * time.Second,
+		write:      15 * time.Second,
+		idle:       60 * time.Second,
+		shutdown:   10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// server returns an http.Server configured by cfg.
+func (cfg serverConfig) server(addr string, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadTimeout:       cfg.read,
+		ReadHeaderTimeout: cfg.readHeader,
+		WriteTimeout:      cfg.write,
+		IdleTimeout:       cfg.idle,
+		MaxHeaderBytes:    cfg.maxHeader,
+	}
+}
+
+// ReadTimeout sets the servers http.Server.ReadTimeout, 15s by default.
+func ReadTimeout(d time.Duration) ServerOption {
+	return func(c *serverConfig) { c.read = d }
+}
+
+// ReadHeaderTimeout sets the servers http.Server.ReadHeaderTimeout, 5s by
+// default.
+func ReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(c *serverConfig) { c.readHeader = d }
+}
+
+// MaxHeaderBytes sets the servers http.Server.MaxHeaderBytes, by default that
+// of net/http, http.DefaultMaxHeaderBytes.
+func MaxHeaderBytes(n int) ServerOption {
+	return func(c *serverConfig) { c.maxHeader = n }
+}
+
+// WriteTimeout sets the servers http.Server.WriteTimeout, 15s by default.
+func WriteTimeout(d time.Duration) ServerOption {
+	return func(c *serverConfig) { c.write = d }
+}
+
+// IdleTimeout sets the servers http.Server.IdleTimeout, 60s by default.
+func IdleTimeout(d time.Duration) ServerOption {
+	return func(c *serverConfig) { c.idle = d }
+}
+
+// ShutdownTimeout sets the grace period given to in flight requests upon
+// shutdown, 10s by default.
+func ShutdownTimeout(d time.Duration) ServerOption {
+	return func(c *serverConfig) { c.shutdown = d }
+}
+
+// Server builds the Router, as does Build, and returns an http.Server that
+// serves it on addr, configured by the given options. As with Compose it exits
+// should the Router fail to build.
+func (r *Router) Server(addr string, opts ...ServerOption) *http.Server {
+	mux, err := r.Build()
+	if err != nil {
+		logger().Output(2, err.Error())
+		os.Exit(1)
+	}
+	return newServerConfig(opts).server(addr, mux)
+}
+
+// ListenAndServe serves mux on addr until either the server fails or the
+// process receives SIGINT or SIGTERM, upon which the server is shutdown
+// gracefully. A nil error is returned when the server is shutdown by signal.
+func ListenAndServe(addr string, mux http.Handler, opts ...ServerOption) error {
+	cfg := newServerConfig(opts)
+	server := cfg.server(addr, mux)
+	return cfg.run(listener{server, server.ListenAndServe})
+}
+
+// Serve runs server, with ListenAndServe or, when its TLSConfig holds a
+// certificate, ListenAndServeTLS, until either it fails or ctx is done, upon
+// which it is shutdown gracefully within the grace period set by
+// ShutdownTimeout. A nil error is returned when the server is shutdown because
+// ctx is done, otherwise the error of the server.
+func Serve(ctx context.Context, server *http.Server, opts ...ServerOption) error {
+	return newServerConfig(opts).runContext(ctx, serverListener(server))
+}
+
+// ListenAndServeTLS serves mux over TLS on httpsAddr whilst serving on httpAddr
+// a handler that redirects all requests to their https equivalent upon the port
+// of httpsAddr, both are shutdown gracefully upon SIGINT or SIGTERM or when
+// either server fails, the first error encountered being returned.
+func ListenAndServeTLS(httpsAddr, httpAddr, certFile, keyFile string, mux http.Handler) error {
+	_, httpsPort, err := net.SplitHostPort(httpsAddr)
+	if err != nil {
+		return err
+	}
+	if _, _, err := net.SplitHostPort(httpAddr); err != nil {
+		return err
+	}
+	cfg := newServerConfig(nil)
+	tls := cfg.server(httpsAddr, mux)
+	redirect := cfg.server(httpAddr, RedirectWith(httpsRedirect(httpsPort)))
+	return cfg.run(
+		listener{tls, func() error {
+			return tls.ListenAndServeTLS(certFile, keyFile)
+		}},
+		listener{redirect, redirect.ListenAndServe},
+	)
+}
+
+// httpsRedirect configures the redirect of every request received by the http
+// server of ListenAndServeTLS to httpsPort, whether or not its Host gives a
+// port, the port being dropped from the target when it is the default, 443.
+func httpsRedirect(httpsPort string) RedirectConfig {
+	if httpsPort == "443" {
+		return RedirectConfig{StripPort: true}
+	}
+	return RedirectConfig{Port: httpsPort}
+}
+
+// listener pairs a server with the function that starts it.
+type listener struct {
+	server *http.Server
+	start  func() error
+}
+
+// serverListener returns a listener that starts server with ListenAndServe or,
+// when its TLSConfig holds a certificate, ListenAndServeTLS.
+func serverListener(server *http.Server) listener {
+	start := server.ListenAndServe
+	if server.TLSConfig != nil && (len(server.TLSConfig.Certificates) > 0 ||
+		server.TLSConfig.GetCertificate != nil) {
+		start = func() error { return server.ListenAndServeTLS("", "") }
+	}
+	return listener{server, start}
+}
+
+// run starts each listener in its own go routine, running until either one
+// fails or the process receives SIGINT or SIGTERM, all of the servers are then
+// shutdown.
+func (cfg serverConfig) run(ls ...listener) error {
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return cfg.runContext(ctx, ls...)
+}
+
+// runContext starts each listener in its own go routine, running until either
+// one fails or ctx is done, all of the servers are then shutdown. When ctx is
+// done cfg.drain, if set, is called before the shutdown begins.
+func (cfg serverConfig) runContext(ctx context.Context, ls ...listener) error {
+	errc := make(chan error, len(ls))
+	for _, l := range ls {
+		go func() {
+			errc <- l.start()
+		}()
+	}
+	var first error
+	select {
+	case first = <-errc:
+	case <-ctx.Done():
+		if cfg.drain != nil {
+			cfg.drain()
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.shutdown)
+	defer cancel()
+	for _, l := range ls {
+		if err := l.server.Shutdown(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	if first != nil && !errors.Is(first, http.ErrServerClosed) {
+		return first
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package srv
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		port, host, want string
+	}{
+		{"8443", "example.com", "https://example.com:8443/x?q=1"},
+		{"8443", "example.com:80", "https://example.com:8443/x?q=1"},
+		{"8443", "example.com:8080", "https://example.com:8443/x?q=1"},
+		{"8443", "[::1]", "https://[::1]:8443/x?q=1"},
+		{"443", "example.com", "https://example.com/x?q=1"},
+		{"443", "example.com:80", "https://example.com/x?q=1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/x?q=1", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		RedirectWith(httpsRedirect(tt.port))(rec, req)
+		if got := rec.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s to port %s: got %q want %q", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
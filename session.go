@@ -0,0 +1,356 @@
+package srv
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionState holds the values of a client session, it is safe for
+// concurrent use.
+type SessionState struct {
+	mu        sync.Mutex
+	id        string
+	values    map[string]any
+	modified  bool
+	destroyed bool
+}
+
+// Get returns the value stored under key.
+func (s *SessionState) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set stores val under key.
+func (s *SessionState) Set(key string, val any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]any)
+	}
+	s.values[key] = val
+	s.modified = true
+}
+
+// Delete removes the value stored under key.
+func (s *SessionState) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	s.modified = true
+}
+
+// Destroy removes the session from the store and expires its cookie.
+func (s *SessionState) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = nil
+	s.destroyed = true
+}
+
+// SessionStore loads and saves sessions, the value of the session cookie
+// being whatever the store requires to find the session again, an ID or the
+// encoded session itself.
+type SessionStore interface {
+	// Load returns the values of the session identified by the cookie
+	// value, or an error if there is no such session.
+	Load(value string) (id string, values map[string]any, err error)
+	// Save stores the values of the session returning the cookie value.
+	Save(id string, values map[string]any) (value string, err error)
+	// Delete removes the session.
+	Delete(id string) error
+}
+
+// SessionOption configures the Session Mware.
+type SessionOption func(*sessionConfig)
+
+type sessionConfig struct {
+	name   string
+	maxAge time.Duration
+	secure bool
+}
+
+// SessionCookieName sets the name of the session cookie, "session" by default.
+func SessionCookieName(name string) SessionOption {
+	return func(c *sessionConfig) { c.name = name }
+}
+
+// SessionMaxAge sets the lifetime of the session cookie, 24 hours by default.
+func SessionMaxAge(d time.Duration) SessionOption {
+	return func(c *sessionConfig) { c.maxAge = d }
+}
+
+// SessionSecure restricts the session cookie to https.
+func SessionSecure() SessionOption {
+	return func(c *sessionConfig) { c.secure = true }
+}
+
+type sessionKey struct{}
+
+// Session returns an Mware that loads the session of the request from store,
+// making it available to handlers by SessionFrom. A session that has been
+// modified is saved, and its cookie set, before the response header is
+// written, a new session being created for requests that have none. The
+// session of a request aborted with Abort is not saved.
+func Session(store SessionStore, opts ...SessionOption) Mware {
+	cfg := sessionConfig{name: "session", maxAge: 24 * time.Hour}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			s := &SessionState{}
+			if c, err := req.Cookie(cfg.name); err == nil {
+				if id, values, err := store.Load(c.Value); err == nil {
+					s.id, s.values = id, values
+				}
+			}
+			if s.id == "" {
+				s.id = newSessionID()
+			}
+			w := &sessionWriter{ResponseWriter: newResponseWriter(res), s: s, store: store, cfg: &cfg}
+			ctx := context.WithValue(req.Context(), sessionKey{}, s)
+			next(w, req.WithContext(ctx))
+			w.save()
+		}
+	}
+}
+
+// SessionFrom returns the session stored in ctx by the Session Mware, or nil.
+func SessionFrom(ctx context.Context) *SessionState {
+	s, _ := ctx.Value(sessionKey{}).(*SessionState)
+	return s
+}
+
+// sessionWriter saves the session before the response header is written.
+type sessionWriter struct {
+	*ResponseWriter
+	s     *SessionState
+	store SessionStore
+	cfg   *sessionConfig
+	saved bool
+}
+
+// save stores the session if it has been modified or destroyed, setting or
+// expiring its cookie, it does so only once and not at all once aborted.
+func (w *sessionWriter) save() {
+	if w.saved {
+		return
+	}
+	w.saved = true
+	if w.aborted {
+		return
+	}
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	cookie := &http.Cookie{
+		Name:     w.cfg.name,
+		Path:     "/",
+		Secure:   w.cfg.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	switch {
+	case w.s.destroyed:
+		w.store.Delete(w.s.id)
+		cookie.MaxAge = -1
+	case w.s.modified:
+		value, err := w.store.Save(w.s.id, w.s.values)
+		if err != nil {
+			return
+		}
+		cookie.Value = value
+		cookie.MaxAge = int(w.cfg.maxAge / time.Second)
+	default:
+		return
+	}
+	if w.status == 0 {
+		http.SetCookie(w.ResponseWriter, cookie)
+	}
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.save()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *sessionWriter) FlushError() error {
+	w.save()
+	return w.ResponseWriter.FlushError()
+}
+
+// newSessionID returns 32 random bytes base64 encoded.
+func newSessionID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// errNoSession is returned by a SessionStore when a session is not found.
+var errNoSession = errors.New(pkg + ": no such session")
+
+// MemoryStore is a SessionStore that holds sessions in memory, the cookie
+// carrying only the session ID. Expired sessions are removed as they are
+// loaded and by a sweep of the whole store made at most once per ttl.
+type MemoryStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions map[string]memorySession
+	sweep    time.Time
+}
+
+type memorySession struct {
+	values  map[string]any
+	expires time.Time
+}
+
+// NewMemoryStore returns a MemoryStore whose sessions expire after ttl.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, sessions: make(map[string]memorySession)}
+}
+
+// Load returns the values of the session with the given ID.
+func (m *MemoryStore) Load(id string) (string, map[string]any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.sweep) > m.ttl {
+		for k, s := range m.sessions {
+			if now.After(s.expires) {
+				delete(m.sessions, k)
+			}
+		}
+		m.sweep = now
+	}
+	s, ok := m.sessions[id]
+	if !ok {
+		return "", nil, errNoSession
+	}
+	if now.After(s.expires) {
+		delete(m.sessions, id)
+		return "", nil, errNoSession
+	}
+	return id, copyValues(s.values), nil
+}
+
+// copyValues returns a copy of the values of a session, such that the store
+// never shares a map with a request.
+func copyValues(values map[string]any) map[string]any {
+	c := make(map[string]any, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
+
+// Save stores a copy of the values of the session, returning its ID.
+func (m *MemoryStore) Save(id string, values map[string]any) (string, error) {
+	values = copyValues(values)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = memorySession{values: values, expires: time.Now().Add(m.ttl)}
+	return id, nil
+}
+
+// Delete removes the session with the given ID.
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// CookieStore is a SessionStore that keeps the session in the cookie itself,
+// gob encoded and signed with HMAC-SHA256. The values are signed, not
+// encrypted, and so are readable by the client, types other than the built in
+// types must be registered with gob.Register. The time at which the session
+// expires is signed with it, such that a cookie kept by the client beyond its
+// lifetime is refused.
+type CookieStore struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewCookieStore returns a CookieStore that signs its cookies with key, the
+// sessions expiring ttl after they were last saved. It panics if key is empty,
+// as any client could then sign its own sessions.
+func NewCookieStore(key []byte, ttl time.Duration) *CookieStore {
+	if len(key) == 0 {
+		panic(pkg + ": NewCookieStore: empty key")
+	}
+	return &CookieStore{key: key, ttl: ttl}
+}
+
+type cookieSession struct {
+	ID      string
+	Values  map[string]any
+	Expires time.Time
+}
+
+func (c *CookieStore) sign(b []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+// Load verifies and decodes the session from the cookie value, refusing it once
+// it has expired.
+func (c *CookieStore) Load(value string) (string, map[string]any, error) {
+	data, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", nil, errNoSession
+	}
+	b, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return "", nil, errNoSession
+	}
+	want, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(c.sign(b), want) {
+		return "", nil, errNoSession
+	}
+	var s cookieSession
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&s); err != nil {
+		return "", nil, err
+	}
+	if time.Now().After(s.Expires) {
+		return "", nil, errNoSession
+	}
+	return s.ID, s.Values, nil
+}
+
+// Save encodes and signs the session returning the cookie value.
+func (c *CookieStore) Save(id string, values map[string]any) (string, error) {
+	var buf bytes.Buffer
+	s := cookieSession{ID: id, Values: values, Expires: time.Now().Add(c.ttl)}
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		return "", err
+	}
+	b := buf.Bytes()
+	return base64.RawURLEncoding.EncodeToString(b) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(b)), nil
+}
+
+// Delete does nothing, the session being removed with its cookie.
+func (c *CookieStore) Delete(id string) error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package srv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreCopiesValues(t *testing.T) {
+	m := NewMemoryStore(time.Minute)
+	values := map[string]any{"a": 1}
+	id, err := m.Save("id", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values["a"] = 2
+	_, got, err := m.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["a"] != 1 {
+		t.Errorf("store shares the saved map: got %v", got["a"])
+	}
+}
+
+func TestMemoryStoreExpires(t *testing.T) {
+	m := NewMemoryStore(time.Millisecond)
+	id, _ := m.Save("id", map[string]any{})
+	time.Sleep(5 * time.Millisecond)
+	if _, _, err := m.Load(id); err == nil {
+		t.Error("expired session loaded")
+	}
+}
+
+func TestCookieStoreExpires(t *testing.T) {
+	c := NewCookieStore([]byte("key"), time.Minute)
+	value, err := c.Save("id", map[string]any{"a": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, values, err := c.Load(value)
+	if err != nil || id != "id" || values["a"] != "b" {
+		t.Fatalf("got %q %v %v", id, values, err)
+	}
+
+	c = NewCookieStore([]byte("key"), -time.Minute)
+	value, _ = c.Save("id", map[string]any{})
+	if _, _, err := c.Load(value); err == nil {
+		t.Error("expired cookie loaded")
+	}
+}
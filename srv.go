@@ -1,14 +1,42 @@
 package srv
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
 )
 
 const pkg = "srv"
 
+var diag atomic.Pointer[log.Logger]
+
+// SetLogger sets the logger to which the package writes its diagnostics, the
+// errors upon which Handle, Add and Compose exit and those of handlers
+// recovered from panics or failed, by default and when l is nil the standard
+// logger.
+func SetLogger(l *log.Logger) {
+	diag.Store(l)
+}
+
+// logger returns the logger set by SetLogger or else the standard logger.
+func logger() *log.Logger {
+	if l := diag.Load(); l != nil {
+		return l
+	}
+	return log.Default()
+}
+
 // Mware
 type Mware func(http.HandlerFunc) http.HandlerFunc
 type Routes []Route
@@ -17,102 +45,644 @@ type Routes []Route
 // or 'object' of the srv package.
 type Route struct {
 	pattern string
+	name    string
+	methods []string
 	fn      http.HandlerFunc
+	layers  int
+	// unchecked is the handler as it was when Method was first called, and
+	// outer the Mware applied since, such that further calls to Method
+	// replace the method check rather than adding another.
+	unchecked http.HandlerFunc
+	outer     []Mware
+	// generated is set upon routes created by the Router, such as those of
+	// AutoOptions, rather than added by the user.
+	generated bool
 }
 
 // Handle takes a pattern and either an http.Handler or a http.HandlerFunc or a
 // function that meets the http.HandlerFunc type requirments returning a Route
 // which contins the given object wrappend with any Mware that have been passed
 // in after the Handler or the HandlerFunc object.
+//
+// The pattern is passed through to the http.ServeMux unaltered, as such the Go
+// 1.22 pattern syntax may be used, a pattern may be qualified by a method,
+// "GET /items/{id}", and any wildcards read from within the handler using
+// req.PathValue.
 func Handle(pattern string, h any, mw ...Mware) *Route {
+	route, err := handle(pattern, h, mw...)
+	if err != nil {
+		logger().Output(2, err.Error())
+		os.Exit(1)
+	}
+	return route
+}
+
+// HandleE is as Handle but returns an error rather than exiting when the given
+// handler is nil or not of a supported type, the pattern is also checked with
+// ValidatePattern.
+func HandleE(pattern string, h any, mw ...Mware) (*Route, error) {
+	if err := ValidatePattern(pattern); err != nil {
+		return nil, err
+	}
+	return handle(pattern, h, mw...)
+}
+
+// Handles returns a Route at each of the given patterns for h, which is wrapped
+// with the given Mware only once, the resulting handler being shared by all of
+// the routes.
+func Handles(patterns []string, h any, mw ...Mware) Routes {
+	var route *Route
+	if len(patterns) > 0 {
+		route = Handle(patterns[0], h, mw...)
+	}
+	routes := make(Routes, len(patterns))
+	for i, p := range patterns {
+		routes[i] = *route
+		routes[i].pattern = p
+	}
+	return routes
+}
+
+// RoutesFromMap returns a Route for each pattern and handler of m, each wrapped
+// with the given Mware, sorted by pattern so that their order is deterministic.
+func RoutesFromMap(m map[string]http.HandlerFunc, mw ...Mware) Routes {
+	patterns := make([]string, 0, len(m))
+	for p := range m {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	routes := make(Routes, len(patterns))
+	for i, p := range patterns {
+		routes[i] = *Handle(p, m[p], mw...)
+	}
+	return routes
+}
+
+// HandlerE is a handler that returns any error that it encounters rather than
+// writing the error response itself.
+type HandlerE func(http.ResponseWriter, *http.Request) error
+
+// HandleErr returns a Route at pattern for the handler h wrapped with the given
+// Mware, any error returned by h being passed to errFn to write the response.
+// When errFn is nil the error is logged and a 500 Internal Server Error is
+// written without revealing the error to the client. ErrAborted is not passed
+// to errFn, the response having already been written by Abort.
+func HandleErr(pattern string, h HandlerE, errFn func(http.ResponseWriter, *http.Request, error), mw ...Mware) *Route {
+	if h == nil {
+		logger().Output(2, fmt.Sprintf("%s: %q: nil handler %T", pkg, pattern, h))
+		os.Exit(1)
+	}
+	if errFn == nil {
+		errFn = internalError
+	}
+	fn := func(res http.ResponseWriter, req *http.Request) {
+		if err := h(res, req); err != nil && !errors.Is(err, ErrAborted) {
+			errFn(res, req, err)
+		}
+	}
+	return Handle(pattern, fn, mw...)
+}
+
+// internalError is the default error handler of HandleErr.
+func internalError(res http.ResponseWriter, req *http.Request, err error) {
+	logger().Printf("%s: %s %s: %v", pkg, req.Method, req.URL.Path, err)
+	http.Error(res, http.StatusText(http.StatusInternalServerError),
+		http.StatusInternalServerError)
+}
+
+// ValidatePattern checks that pattern is well formed: that it is not empty,
+// that its path begins with "/", optionally preceded by a method and or a
+// host, and that its wildcards are well formed and uniquely named.
+func ValidatePattern(pattern string) error {
+	fail := func(msg string) error {
+		return fmt.Errorf("%s: invalid pattern %q: %s", pkg, pattern, msg)
+	}
+	if strings.TrimSpace(pattern) == "" {
+		return fail("empty pattern")
+	}
+	method, rest := splitPattern(pattern)
+	for i := 0; i < len(method); i++ {
+		if c := method[i]; c <= ' ' || c > '~' || strings.IndexByte("()<>@,;:\\\"/[]?={}", c) >= 0 {
+			return fail("invalid method " + strconv.Quote(method))
+		}
+	}
+	i := strings.IndexByte(rest, '/')
+	if i < 0 {
+		return fail("path must begin with \"/\"")
+	}
+	if strings.ContainsAny(rest[:i], "{}") {
+		return fail("host may not contain wildcards")
+	}
+	segs := strings.Split(rest[i+1:], "/")
+	seen := make(map[string]bool)
+	for j, seg := range segs {
+		if !strings.ContainsAny(seg, "{}") {
+			continue
+		}
+		if seg[0] != '{' || seg[len(seg)-1] != '}' || strings.Count(seg, "{") != 1 {
+			return fail("wildcard must be a whole segment")
+		}
+		name := seg[1 : len(seg)-1]
+		if name == "$" {
+			if j != len(segs)-1 {
+				return fail("{$} must be at the end")
+			}
+			continue
+		}
+		name, multi := strings.CutSuffix(name, "...")
+		if multi && j != len(segs)-1 {
+			return fail("{" + name + "...} must be at the end")
+		}
+		if name == "" || !isIdent(name) {
+			return fail("bad wildcard name " + strconv.Quote(name))
+		}
+		if seen[name] {
+			return fail("duplicate wildcard name " + strconv.Quote(name))
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// isIdent reports whether s is a Go identifier, as required of wildcard names.
+func isIdent(s string) bool {
+	for i, c := range s {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// handle converts h into an http.HandlerFunc and returns it as a Route wrapped
+// with the given Mware.
+func handle(pattern string, h any, mw ...Mware) (*Route, error) {
+	if isNil(h) {
+		return nil, fmt.Errorf("%s: %q: nil handler %T", pkg, pattern, h)
+	}
 	var fn http.HandlerFunc
 	switch t := h.(type) {
-	case http.Handler:
-		fn = func(res http.ResponseWriter, req *http.Request) {
-			t.ServeHTTP(res, req)
-		}
 	case http.HandlerFunc:
 		fn = t
 	case func(http.ResponseWriter, *http.Request):
 		fn = http.HandlerFunc(t)
+	case http.Handler:
+		fn = t.ServeHTTP
 	default:
-		msg := fmt.Sprintf("require either http.Handler or http.HandlerFunc got: %T", h)
-		log.Output(2, msg)
-		os.Exit(1)
+		return nil, fmt.Errorf("%s: %q: require either http.Handler or "+
+			"http.HandlerFunc got: %T", pkg, pattern, h)
 	}
-	route := &Route{pattern, fn}
+	route := &Route{pattern: pattern, fn: fn}
 	for _, fn := range mw {
 		route.fn = fn(route.fn)
 	}
-	return route
+	route.layers = len(mw)
+	return route, nil
+}
+
+// isNil reports whether h is nil, or a typed nil such as an unassigned
+// http.HandlerFunc or a nil pointer stored in an http.Handler.
+func isNil(h any) bool {
+	if h == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(h); v.Kind() {
+	case reflect.Func, reflect.Pointer, reflect.Map, reflect.Chan,
+		reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
 }
 
 // Wrap wraps the Route with the given Mware's.
 func (r *Route) Wrap(mw ...Mware) *Route {
 	for _, fn := range mw {
-		r.fn = fn(r.fn)
+		r.wrap(fn)
+	}
+	r.layers += len(mw)
+	return r
+}
+
+// wrap wraps the Route with mw, recording it when a method check is in place
+// so that it may be reapplied should Method replace the check.
+func (r *Route) wrap(mw Mware) {
+	r.fn = mw(r.fn)
+	if r.unchecked != nil {
+		r.outer = extend(r.outer, mw)
+	}
+}
+
+// Pattern returns the pattern at which the Route is registered.
+func (r *Route) Pattern() string {
+	return r.pattern
+}
+
+// Handler returns the Route's handler, wrapped with any Mware applied to it so
+// far.
+func (r *Route) Handler() http.HandlerFunc {
+	return r.fn
+}
+
+// MiddlewareCount returns the number of Mware that wrap the Route's handler.
+// For a Route as added to a Router the count includes only those applied to the
+// Route itself, that of the Route as registered, wrapped also by its Groups and
+// the Router, is given by the Middleware field of its RouteInfo.
+func (r *Route) MiddlewareCount() int {
+	return r.layers
+}
+
+type patternKey struct{}
+
+// Pattern returns the pattern of the route that matched req, as it was
+// registered with all group and mount prefixes applied, such that logging and
+// metrics may group requests by route rather than by path. The pattern is
+// stored in the request context as the route is registered, by Compose, Build,
+// ComposeOn or Routes.Serve, and so is available whichever Muxer matched the
+// route. An empty string is returned if req was not routed by one of them.
+func Pattern(req *http.Request) string {
+	p, _ := req.Context().Value(patternKey{}).(string)
+	return p
+}
+
+// withPattern returns fn wrapped so as to store pattern in the context of each
+// request, to be read by Pattern.
+func withPattern(pattern string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), patternKey{}, pattern)
+		fn(res, req.WithContext(ctx))
+	}
+}
+
+// Name sets the name by which the Route may be referred to when generating
+// URLs with Router.URL.
+func (r *Route) Name(name string) *Route {
+	r.name = name
+	return r
+}
+
+// Method restricts the Route to the given HTTP methods, requests made with any
+// other method receive a 405 Method Not Allowed along with an Allow header
+// listing the permitted methods. Further calls add to the methods permitted,
+// the Route having a single check placed where Method was first called.
+func (r *Route) Method(methods ...string) *Route {
+	for _, m := range methods {
+		r.methods = extend(r.methods, strings.ToUpper(m))
+	}
+	if r.unchecked == nil {
+		r.unchecked = r.fn
+	}
+	allowed := append([]string(nil), r.methods...)
+	allow := strings.Join(allowed, ", ")
+	next := r.unchecked
+	r.fn = func(res http.ResponseWriter, req *http.Request) {
+		for _, m := range allowed {
+			if strings.EqualFold(req.Method, m) {
+				next(res, req)
+				return
+			}
+		}
+		res.Header().Set("Allow", allow)
+		http.Error(res, http.StatusText(http.StatusMethodNotAllowed),
+			http.StatusMethodNotAllowed)
+	}
+	for _, mw := range r.outer {
+		r.fn = mw(r.fn)
 	}
 	return r
 }
 
+// OnPanic wraps the Route such that should its handler panic fallback is
+// called to respond in its place. The headers are first restored to those set
+// before the handler was called, by outer middleware such as RequestID,
+// discarding any that the handler set, however, if the handler has already
+// written the header of the response it can no longer be replaced, fallback is
+// then not called and the response is left as written. http.ErrAbortHandler is
+// re-panicked.
+func (r *Route) OnPanic(fallback http.HandlerFunc) *Route {
+	r.wrap(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			rec := newResponseWriter(res)
+			saved := res.Header().Clone()
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				if rec.status != 0 {
+					logger().Printf("%s: panic serving %s after response was "+
+						"written: %v", pkg, req.URL.Path, v)
+					return
+				}
+				h := res.Header()
+				for k := range h {
+					delete(h, k)
+				}
+				for k, v := range saved {
+					h[k] = v
+				}
+				fallback(res, req)
+			}()
+			next(rec, req)
+		}
+	})
+	return r
+}
+
+// Timeout wraps the Route with the Timeout Mware, giving its handler d in which
+// to respond. Should the route also be wrapped by a Timeout applied to its
+// group or router the smaller duration wins, the deadline of the inner
+// request context being bounded by that of the outer, whichever expires first
+// responding with the 503.
+func (r *Route) Timeout(d time.Duration) *Route {
+	return r.Wrap(Timeout(d))
+}
+
+// Consumes wraps the Route with the Consumes Mware, rejecting requests whose
+// body is not of one of the given media types.
+func (r *Route) Consumes(types ...string) *Route {
+	return r.Wrap(Consumes(types...))
+}
+
+// Cache wraps the Route with the CacheControl Mware, setting the Cache-Control
+// header of its responses to value.
+func (r *Route) Cache(value string, opts ...HeadersOption) *Route {
+	return r.Wrap(CacheControl(value, opts...))
+}
+
 // Group is an intermedary object which may contain any one of, a slice of
 // Groups, Routes or Mwares, The Groups will wrap all of the its sub Groups and
 // Routes with any Mwares that are applied to it using Wrap.
+//
+// As with Router, a Group is built from a single go routine, when added to a
+// Router or another Group it is copied, subsequent changes to it are not seen
+// by the copy.
 type Group struct {
-	groups []Group
-	routes []Route
-	wrap   []Mware
+	prefix   string
+	groups   []Group
+	routes   []Route
+	wrap     []Mware
+	notFound http.HandlerFunc
+}
+
+// Prefix sets a path prefix that is prepended to the pattern of every route
+// and sub group that the Group contains, prefixes of nested groups accumulate.
+func (g *Group) Prefix(p string) *Group {
+	g.prefix = p
+	return g
+}
+
+// NotFound registers h at the root of the Group's prefix, "/api/", where it
+// receives every request under the prefix that no other route matches. Being
+// the least specific pattern under the prefix, any more specific route of the
+// Group, or of the Router, takes precedence, a Group that registers its own
+// "/" route may not also have a NotFound handler. The handler is wrapped by
+// the Group's middleware as are its routes.
+func (g *Group) NotFound(h http.HandlerFunc) *Group {
+	g.notFound = h
+	return g
 }
 
 // Wrap wraps all sub groups and routes withing the group with the give Mware.
+// A Group is copied when added to a Router or another Group, and its Mware
+// copied when extended, so that a Group reused in several places is wrapped
+// only by the Mware it held when each was added, and only once by each.
 func (g *Group) Wrap(mw ...Mware) *Group {
-	g.wrap = append(g.wrap, mw...)
+	g.wrap = extend(g.wrap, mw...)
 	return g
 }
 
-// Add takes either Group as sub groups or Routes and adds them to this Group.
+// Add takes either Group as sub groups or Routes and adds them to this Group,
+// any Mware given are added as though by Wrap.
 func (g *Group) Add(v ...any) *Group {
+	if _, err := g.AddE(v...); err != nil {
+		logger().Fatal(err)
+	}
+	return g
+}
+
+// AddE is as Add but returns an error naming the index and type of every
+// argument that can not be added, rather than exiting, in which case none of
+// the arguments are added.
+func (g *Group) AddE(v ...any) (*Group, error) {
+	if err := checkAdd(v); err != nil {
+		return g, err
+	}
 	for _, v := range v {
 		switch t := v.(type) {
 		case []Group:
-			g.groups = append(g.groups, t...)
+			g.groups = extend(g.groups, t...)
 		case *Group:
-			g.groups = append(g.groups, *t)
+			g.groups = extend(g.groups, *t)
 		case []Route:
-			g.routes = append(g.routes, t...)
+			g.routes = extend(g.routes, t...)
+		case Routes:
+			g.routes = extend(g.routes, t...)
 		case *Route:
-			g.routes = append(g.routes, *t)
-		case string:
-			log.Fatal("use " + pkg + ".Handle() to add an endpoint")
-		default:
-			log.Fatalf("unknown type: %T", t)
+			g.routes = extend(g.routes, *t)
+		case Mware:
+			g.wrap = extend(g.wrap, t)
+		case func(http.HandlerFunc) http.HandlerFunc:
+			g.wrap = extend(g.wrap, Mware(t))
 		}
 	}
-	return g
+	return g, nil
 }
 
-// compose compiles the groups sub groups into routes and wraps them with the
-// groups Mware functions.
+// compose compiles the groups sub groups into routes, prefixes their patterns
+// and wraps them with the groups Mware functions. The groups own routes come
+// first, then those of its sub groups in order, then its NotFound handler.
 func (g *Group) compose() []Route {
+	return g.appendRoutes(make([]Route, 0, g.size()), true)
+}
+
+// appendRoutes appends the composed routes of the group to routes, such that
+// a whole tree of groups is composed into a single slice. When wrap is false
+// the routes are prefixed and their layers counted but the Mware are not
+// applied, for uses that only describe the routes.
+func (g *Group) appendRoutes(routes []Route, wrap bool) []Route {
+	start := len(routes)
+	routes = append(routes, g.routes...)
 	for _, group := range g.groups {
-		g.routes = append(g.routes, group.compose()...)
+		routes = group.appendRoutes(routes, wrap)
 	}
-	for j := range g.routes {
-		for i := range g.wrap {
-			g.routes[j].fn = g.wrap[i](g.routes[j].fn)
+	if g.notFound != nil {
+		routes = append(routes, Route{pattern: "/", fn: g.notFound})
+	}
+	for j := start; j < len(routes); j++ {
+		if g.prefix != "" {
+			routes[j].pattern = prefixPattern(g.prefix, routes[j].pattern)
+		}
+		for i := 0; wrap && i < len(g.wrap); i++ {
+			routes[j].fn = g.wrap[i](routes[j].fn)
 		}
+		routes[j].layers += len(g.wrap)
+	}
+	return routes
+}
+
+// size returns the number of routes that the group composes to.
+func (g *Group) size() int {
+	n := len(g.routes)
+	for _, group := range g.groups {
+		n += group.size()
+	}
+	if g.notFound != nil {
+		n++
 	}
-	return g.routes
+	return n
+}
+
+// extend appends v to a copy of s, such that builders copied from a common base
+// never share, and so overwrite, each others backing arrays.
+func extend[T any](s []T, v ...T) []T {
+	return append(s[:len(s):len(s)], v...)
+}
+
+// splitPattern separates any leading method, as used in Go 1.22 patterns, from
+// the remainder of the pattern.
+func splitPattern(pattern string) (method, path string) {
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		return pattern[:i], strings.TrimLeft(pattern[i+1:], " \t")
+	}
+	return "", pattern
+}
+
+// patternPath returns the literal path of pattern that precedes any wildcard,
+// without its method, host or trailing slash, as may be removed from a request
+// with http.StripPrefix.
+func patternPath(pattern string) string {
+	_, path := splitPattern(pattern)
+	path, _, _ = strings.Cut(path, "{")
+	if i := strings.Index(path, "/"); i > 0 {
+		path = path[i:]
+	}
+	return strings.TrimSuffix(path, "/")
+}
+
+// stripPattern returns a handler that removes from each request the path of the
+// pattern at which its route was finally registered, as given by Pattern,
+// before calling h. Group prefixes and Mount being applied only upon composing,
+// the pattern given when the route was built, fallback, is used only for
+// requests that carry none.
+func stripPattern(fallback string, h http.Handler) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		pattern := Pattern(req)
+		if pattern == "" {
+			pattern = fallback
+		}
+		http.StripPrefix(patternPath(pattern), h).ServeHTTP(res, req)
+	}
+}
+
+// prefixPattern prepends prefix to the path of pattern, normalising the slashes
+// between them and preserving any method and host that qualify the pattern,
+// "GET example.com/x" becoming "GET example.com/api/x".
+func prefixPattern(prefix, pattern string) string {
+	method, path := splitPattern(pattern)
+	host := ""
+	if i := strings.Index(path, "/"); i > 0 {
+		host, path = path[:i], path[i:]
+	}
+	prefix = strings.Trim(prefix, "/")
+	path = strings.TrimLeft(path, "/")
+	joined := "/" + prefix
+	if prefix != "" && path != "" {
+		joined += "/"
+	}
+	joined += path
+	if prefix != "" && path == "" && strings.HasSuffix(pattern, "/") {
+		joined += "/"
+	}
+	joined = host + joined
+	if method != "" {
+		return method + " " + joined
+	}
+	return joined
 }
 
 // Router contains and compiles your applications endpoints, middle ware that
-// wraps the router will be run both first and last in the ordering of the
-// nested function chain upon all of the routes that it contains.
+// wraps the router is applied outside of any group or route middleware, such
+// that it sees every request before and every response after them.
+//
+// The methods that build a Router, Add, Wrap, Use, Mount and Set, are not safe
+// for concurrent use and are expected to be called from a single go routine.
+// Once built, the methods that read the Router, Build, RouteList and URL, do
+// not modify it and may be called concurrently.
 type Router struct {
 	mux    *http.ServeMux
 	groups []Group
 	routes []Route
 	wrap   []Mware
+	use    []Mware
+	byPath []PatternMware
+
+	notFound   http.HandlerFunc
+	notAllowed http.HandlerFunc
+	slash      SlashMode
+	options    bool
+	noHead     bool
+}
+
+// RouterOption configures the Router returned by NewRouter.
+type RouterOption func(*Router)
+
+// RouterUse adds Mware to the Router as would Router.Use.
+func RouterUse(mw ...Mware) RouterOption {
+	return func(r *Router) { r.Use(mw...) }
+}
+
+// RouterNotFound sets the Router's NotFound handler.
+func RouterNotFound(h http.HandlerFunc) RouterOption {
+	return func(r *Router) { r.NotFound(h) }
+}
+
+// RouterMethodNotAllowed sets the Router's MethodNotAllowed handler.
+func RouterMethodNotAllowed(h http.HandlerFunc) RouterOption {
+	return func(r *Router) { r.MethodNotAllowed(h) }
+}
+
+// RouterTrailingSlash sets the Router's trailing slash redirection, as would
+// Router.RedirectTrailingSlash.
+func RouterTrailingSlash(mode SlashMode) RouterOption {
+	return func(r *Router) { r.RedirectTrailingSlash(mode) }
+}
+
+// AutoOptions when enabled has the Router respond to OPTIONS requests for the
+// paths of routes restricted to specific methods, by Route.Method or by a
+// method qualified pattern, with a 204 No Content and an Allow header listing
+// the methods. The responders are wrapped by the Router's middleware, so a
+// CORS Mware added to the Router handles preflight requests first. Paths for
+// which an OPTIONS route is registered, or which have a route that accepts any
+// method, are left to that route.
+func (r *Router) AutoOptions(enable bool) *Router {
+	r.options = enable
+	return r
+}
+
+// AutoHead when enabled, as it is by default, has routes restricted to GET, by
+// Route.Method or by a method qualified pattern, also serve HEAD requests. The
+// handler and all of the middleware of the route, that of the Router
+// included, are run as for a GET request, the headers being sent and the body
+// discarded only as it leaves the outermost Mware, the Content-Length being
+// set from the size of the discarded body when it has not been set. As such
+// Compress and ETag set their headers just as they do for GET. Logger logs the
+// request as HEAD.
+func (r *Router) AutoHead(enable bool) *Router {
+	r.noHead = !enable
+	return r
+}
+
+// NewRouter returns a Router with a new *http.ServeMux, configured by the given
+// options.
+func NewRouter(opts ...RouterOption) *Router {
+	r := &Router{mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // Set sets the given *http.ServeMux server into the router.
@@ -122,42 +692,156 @@ func (r *Router) Set(mux *http.ServeMux) *Router {
 }
 
 // Wrap adds the given Mware to the Router, to be latter applied to evey route
-// and group that the router contains, upon composing.
+// and group that the router contains, upon composing. Each is applied exactly
+// once per route, outside of the route's group and route middleware and
+// inside of any added with Use, the last added being outermost.
 func (r *Router) Wrap(mw ...Mware) *Router {
-	r.wrap = append(r.wrap, mw...)
+	r.wrap = extend(r.wrap, mw...)
+	return r
+}
+
+// PatternMware is as Mware but is given also the pattern of the route that it
+// wraps, as registered with all group prefixes applied, such that middleware
+// such as metrics or authorisation may be scoped by route without reading the
+// pattern from each request.
+type PatternMware func(pattern string, next http.HandlerFunc) http.HandlerFunc
+
+// WrapPattern adds the given PatternMware to the Router, to be applied to every
+// route upon composing with the pattern of that route. They are applied inside
+// of the Mware added with Wrap and Use, outside of all group and route
+// middleware, the last added being outermost.
+func (r *Router) WrapPattern(mw ...PatternMware) *Router {
+	r.byPath = extend(r.byPath, mw...)
+	return r
+}
+
+// Use adds the given Mware to the Router, to be applied to every route that
+// the router contains outside of all other middleware, including that added
+// with Wrap. Mware are run in the order that they are added, the first being
+// outermost and so the first to see the request.
+func (r *Router) Use(mw ...Mware) *Router {
+	r.use = extend(r.use, mw...)
 	return r
 }
 
-// Add adds any given Groups or Routes to the router. Handlers and
-// HanderlerFuncs should be added using Handle.
+// Add adds any given Groups or Routes to the router, any Mware given are added
+// as though by Wrap. Handlers and HanderlerFuncs should be added using Handle.
 func (r *Router) Add(v ...any) *Router {
+	if _, err := r.AddE(v...); err != nil {
+		logger().Fatal(err)
+	}
+	return r
+}
+
+// AddE is as Add but returns an error naming the index and type of every
+// argument that can not be added, rather than exiting, in which case none of
+// the arguments are added.
+func (r *Router) AddE(v ...any) (*Router, error) {
+	if err := checkAdd(v); err != nil {
+		return r, err
+	}
 	for _, in := range v {
 		switch t := in.(type) {
 		case []Group:
-			r.groups = append(r.groups, t...)
+			r.groups = extend(r.groups, t...)
 		case *Group:
-			r.groups = append(r.groups, *t)
+			r.groups = extend(r.groups, *t)
 		case []Route:
-			r.routes = append(r.routes, t...)
+			r.routes = extend(r.routes, t...)
+		case Routes:
+			r.routes = extend(r.routes, t...)
 		case *Route:
-			r.routes = append(r.routes, *t)
-		case http.HandlerFunc:
-			log.Fatal("use " + pkg + ".Handle() to add endpoint")
-		case string:
-			log.Fatal("use " + pkg + ".Handle() to add endpoint")
+			r.routes = extend(r.routes, *t)
+		case Mware:
+			r.wrap = extend(r.wrap, t)
+		case func(http.HandlerFunc) http.HandlerFunc:
+			r.wrap = extend(r.wrap, Mware(t))
+		}
+	}
+	return r, nil
+}
+
+// Handle adds h to the router at pattern wrapped with the given Mware, as would
+// Add(Handle(pattern, h, mw...)).
+func (r *Router) Handle(pattern string, h http.Handler, mw ...Mware) *Router {
+	r.routes = extend(r.routes, *Handle(pattern, h, mw...))
+	return r
+}
+
+// checkAdd returns an error describing each of the arguments given to Add that
+// is not of a type that it accepts.
+func checkAdd(v []any) error {
+	var errs []error
+	for i, in := range v {
+		switch t := in.(type) {
+		case []Group, *Group, []Route, Routes, *Route, Mware,
+			func(http.HandlerFunc) http.HandlerFunc:
+		case string, http.Handler, func(http.ResponseWriter, *http.Request):
+			errs = append(errs, fmt.Errorf("%s: argument %d: got %T, "+
+				"use %s.Handle() to add an endpoint", pkg, i, t, pkg))
 		default:
-			log.Fatalf("unknown type: %T %v", t, t)
+			errs = append(errs, fmt.Errorf("%s: argument %d: unknown type: %T",
+				pkg, i, t))
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// NotFound sets the handler called when no route matches a request, in place of
+// the mux's default 404 Not Found response.
+func (r *Router) NotFound(h http.HandlerFunc) *Router {
+	r.notFound = h
+	return r
+}
+
+// MethodNotAllowed sets the handler called when a route matches the path of a
+// request but not its method, in place of the mux's default 405 Method Not
+// Allowed response, the Allow header being set before it is called. This
+// applies to method qualified patterns, "GET /items", which the mux matches,
+// and not to routes restricted with Route.Method, which respond themselves.
+func (r *Router) MethodNotAllowed(h http.HandlerFunc) *Router {
+	r.notAllowed = h
+	return r
+}
+
+// SlashMode determines how requests whose path differs from that of a route
+// only by a trailing slash are redirected.
+type SlashMode int
+
+const (
+	// SlashNone leaves such requests to the mux.
+	SlashNone SlashMode = iota
+	// SlashStrip redirects "/foo/" to "/foo" when only "/foo" matches.
+	SlashStrip
+	// SlashAdd redirects "/foo" to "/foo/" when only "/foo/" matches.
+	SlashAdd
+)
+
+// RedirectTrailingSlash sets how requests that match no route, but which would
+// were their trailing slash removed or added, are redirected. The root path is
+// never redirected, the query is preserved and requests other than GET and
+// HEAD are redirected with a 308 Permanent Redirect so as to keep their method,
+// others receiving a 301 Moved Permanently.
+func (r *Router) RedirectTrailingSlash(mode SlashMode) *Router {
+	r.slash = mode
+	return r
+}
+
+// Mount composes the routes of sub, wrapped with its own middleware, and adds
+// them to the Router with their patterns prefixed by prefix, upon composing
+// they are then wrapped by the Router's middleware as any other route.
+func (r *Router) Mount(prefix string, sub *Router) *Router {
+	for _, route := range sub.compose() {
+		route.pattern = prefixPattern(prefix, route.pattern)
+		r.routes = extend(r.routes, route)
+	}
 	return r
 }
 
 // Wrap adds the given Mware to all of these Routes.
 func (r *Routes) Wrap(mw ...Mware) *Routes {
 	for j := range *r {
-		for i := range mw {
-			(*r)[j].fn = mw[i]((*r)[j].fn)
-		}
+		(*r)[j].Wrap(mw...)
 	}
 	return r
 }
@@ -166,28 +850,564 @@ func (r *Routes) Wrap(mw ...Mware) *Routes {
 func (r Routes) Serve() *http.ServeMux {
 	server := http.NewServeMux()
 	for i := range r {
-		server.HandleFunc(r[i].pattern, r[i].fn)
+		server.HandleFunc(r[i].pattern, withPattern(r[i].pattern, r[i].fn))
 	}
 	return server
 }
 
+// ServeWith returns a new *http.ServeMux with all of these Routes added as
+// though to a Router created with the given options, such that Mware may be
+// applied with RouterUse and NotFound and MethodNotAllowed handlers given
+// without building a Router. As with Compose it exits should the routes fail
+// to register.
+func (r Routes) ServeWith(opts ...RouterOption) *http.ServeMux {
+	mux, err := NewRouter(opts...).Add(r).Build()
+	if err != nil {
+		logger().Output(2, err.Error())
+		os.Exit(1)
+	}
+	return mux
+}
+
 // Compose adds any given Routes or Groups to the server and then recursivly
 // composes all groups into routes wrapping them with any group specific
 // middleware then finaly it wraps all of its Routes with any Mware that the
 // Router contains.
+//
+// Compose registers the routes upon the Router's mux, which is retained, as
+// such it is not safe to call more than once, use Build to create a new mux
+// from the same Router each time.
+//
+// Routes are registered in a fixed order: first the Router's own routes,
+// including those of mounted routers, in the order that they were added, then
+// each of its groups in the order that they were added. A group likewise
+// gives its own routes, then those of each of its sub groups, depth first, and
+// lastly its NotFound handler. Walk and RouteList follow the same order.
+//
+// A path given both by a bare pattern and by one qualified by a method, "/x"
+// and "GET /x", is accepted by the mux, which sends requests of that method to
+// the one and all others to the bare pattern. This being seldom what was meant
+// Compose logs a warning, whereas ComposeE and Validate return an error.
 func (r *Router) Compose(v ...any) *http.ServeMux {
+	mux, err := r.composeMux(false, v...)
+	if err != nil {
+		logger().Output(2, err.Error())
+		os.Exit(1)
+	}
+	return mux
+}
+
+// ComposeE is as Compose but returns an error rather than exiting when the
+// routes can not be registered, such as when two routes share a pattern, or
+// when a path is given by both a bare pattern and one qualified by a method.
+func (r *Router) ComposeE(v ...any) (*http.ServeMux, error) {
+	return r.composeMux(true, v...)
+}
+
+// composeMux adds v and registers the routes upon the Router's mux, strict
+// determining whether paths given both bare and method qualified are an error
+// or only logged.
+func (r *Router) composeMux(strict bool, v ...any) (*http.ServeMux, error) {
 	if r.mux == nil {
 		r.mux = http.NewServeMux()
 	}
-	r = r.Add(v...)
+	if _, err := r.AddE(v...); err != nil {
+		return nil, err
+	}
+	if err := r.register(r.mux, strict); err != nil {
+		return nil, err
+	}
+	return r.mux, nil
+}
+
+// Build composes the Router's routes onto a new *http.ServeMux, neither the
+// Router nor its mux are modified and so Build is safe to call repeatedly. As
+// with Compose, paths given both bare and method qualified are only logged.
+func (r *Router) Build() (*http.ServeMux, error) {
+	mux := http.NewServeMux()
+	if err := r.register(mux, false); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// Validate checks the Router's routes as Build would register them, without
+// applying their Mware, returning all of the problems found: routes without a
+// handler, invalid patterns, paths given both bare and method qualified and,
+// when there are no others, patterns that duplicate or conflict with one
+// another, as found by registering them upon a throwaway mux.
+func (r *Router) Validate() error {
+	var errs []error
+	for _, pattern := range nilHandlers(r.routes, r.groups) {
+		errs = append(errs, fmt.Errorf("%s: %q: nil handler", pkg, pattern))
+	}
+	routes := r.describe()
+	for _, route := range routes {
+		if err := ValidatePattern(route.pattern); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := shadowed(routes); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return register(http.NewServeMux(), routes)
+}
+
+// nilHandlers returns the patterns, prefixed as they would be composed, of the
+// given routes and those of the given groups that have no handler.
+func nilHandlers(routes []Route, groups []Group) []string {
+	var patterns []string
+	for _, route := range routes {
+		if route.fn == nil {
+			patterns = append(patterns, route.pattern)
+		}
+	}
+	for _, g := range groups {
+		for _, pattern := range nilHandlers(g.routes, g.groups) {
+			if g.prefix != "" {
+				pattern = prefixPattern(g.prefix, pattern)
+			}
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// Muxer is implemented by any router upon which routes may be registered,
+// *http.ServeMux being the default.
+type Muxer interface {
+	Handle(pattern string, h http.Handler)
+}
+
+// ComposeOn composes the Router's routes, as does Build, registering them upon
+// mux, so that a router other than *http.ServeMux may be used to match them.
+// The patterns must be of a form that mux understands. When a NotFound or
+// MethodNotAllowed handler or a SlashMode is set the routes are matched by an
+// inner *http.ServeMux and mux receives only a handler at "/".
+func (r *Router) ComposeOn(mux Muxer) error {
+	return r.register(mux, false)
+}
+
+// register composes the Router's routes and registers them upon mux, when a
+// NotFound or MethodNotAllowed handler or a SlashMode is set the routes are
+// instead registered upon an inner mux, mux receiving at "/" a handler that
+// calls them when the inner mux finds no match. Paths given both bare and
+// method qualified are an error when strict, else a warning is logged.
+func (r *Router) register(mux Muxer, strict bool) error {
+	routes := r.compose()
+	if err := shadowed(routes); err != nil {
+		if strict {
+			return err
+		}
+		logger().Printf("warning: %v", err)
+	}
+	if r.notFound == nil && r.notAllowed == nil && r.slash == SlashNone {
+		return register(mux, routes)
+	}
+	inner := http.NewServeMux()
+	if err := register(inner, routes); err != nil {
+		return err
+	}
+	var notFound, notAllowed http.HandlerFunc
+	if r.notFound != nil {
+		notFound = r.wrapFn(r.notFound)
+	}
+	if r.notAllowed != nil {
+		notAllowed = r.wrapFn(r.notAllowed)
+	}
+	return register(mux, []Route{{
+		pattern: "/",
+		fn:      fallback(inner, notFound, notAllowed, r.slash),
+	}})
+}
+
+// fallback returns a handler that serves requests with mux, save those for
+// which it has no match, which are redirected according to slash or passed to
+// notFound or notAllowed when set. Those requests have no pattern, the "/" at
+// which the fallback is itself registered being cleared.
+func fallback(mux *http.ServeMux, notFound, notAllowed http.HandlerFunc, slash SlashMode) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		req = req.WithContext(context.WithValue(req.Context(), patternKey{}, ""))
+		if h, pattern := mux.Handler(req); pattern == "" {
+			p := &probe{header: make(http.Header)}
+			h.ServeHTTP(p, req)
+			switch {
+			case p.status == http.StatusNotFound && redirectSlash(mux, res, req, slash):
+				return
+			case p.status == http.StatusNotFound && notFound != nil:
+				notFound(res, req)
+				return
+			case p.status == http.StatusMethodNotAllowed && notAllowed != nil:
+				res.Header()["Allow"] = p.header["Allow"]
+				notAllowed(res, req)
+				return
+			}
+		}
+		mux.ServeHTTP(res, req)
+	}
+}
+
+// redirectSlash redirects the request if, according to mode, its path with a
+// trailing slash removed or added matches a route of mux, reporting whether it
+// did so. Nothing is written should the client have gone away.
+func redirectSlash(mux *http.ServeMux, res http.ResponseWriter, req *http.Request, mode SlashMode) bool {
+	// The slash is trimmed or added upon both the decoded and escaped path,
+	// the target being the escaped form so that an escaped "?" or "/" remains
+	// part of the path.
+	path, escaped := req.URL.Path, req.URL.EscapedPath()
+	switch {
+	case path == "/":
+		return false
+	case mode == SlashStrip && strings.HasSuffix(path, "/"):
+		path = strings.TrimSuffix(path, "/")
+		escaped = strings.TrimSuffix(escaped, "/")
+	case mode == SlashAdd && !strings.HasSuffix(path, "/"):
+		path += "/"
+		escaped += "/"
+	default:
+		return false
+	}
+	alt := *req
+	u := *req.URL
+	u.Path, u.RawPath = path, escaped
+	alt.URL = &u
+	if _, pattern := mux.Handler(&alt); pattern == "" {
+		return false
+	}
+	code := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	target := u.EscapedPath()
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	if req.Context().Err() == nil {
+		http.Redirect(res, req, target, code)
+	}
+	return true
+}
+
+// register checks the routes for duplicate patterns and registers them upon
+// mux, each with its pattern stored for Pattern, returning as an error any
+// panic raised by the mux during registration.
+func register(mux Muxer, routes []Route) (err error) {
+	if err := duplicates(routes); err != nil {
+		return err
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%s: %v", pkg, v)
+		}
+	}()
+	for j := range routes {
+		mux.Handle(routes[j].pattern, withPattern(routes[j].pattern, routes[j].fn))
+	}
+	return nil
+}
+
+// duplicates returns an error naming the first pattern that is used by more
+// than one of the given routes, patterns qualified by differing methods are
+// not in conflict.
+func duplicates(routes []Route) error {
+	seen := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		method, path := splitPattern(route.pattern)
+		key := method + " " + path
+		if seen[key] {
+			return fmt.Errorf("%s: multiple registrations for pattern %q",
+				pkg, route.pattern)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// shadowed returns an error naming the first path that is used both by a
+// pattern qualified by a method and by a bare pattern. The mux then sends the
+// requests of the qualified methods to the one, and of all others to the bare
+// pattern, which is seldom what was meant and easily missed. Routes generated
+// by the Router, such as those of AutoOptions, are not considered.
+func shadowed(routes []Route) error {
+	bare := make(map[string]string)
+	qualified := make(map[string]string)
+	for _, route := range routes {
+		if route.generated {
+			continue
+		}
+		method, path := splitPattern(route.pattern)
+		if method == "" {
+			bare[path] = route.pattern
+		} else if _, ok := qualified[path]; !ok {
+			qualified[path] = route.pattern
+		}
+	}
+	for _, route := range routes {
+		_, path := splitPattern(route.pattern)
+		b, q := bare[path], qualified[path]
+		if b != "" && q != "" {
+			return fmt.Errorf("%s: patterns %q and %q share a path, %q "+
+				"receiving every request to it not made with the method "+
+				"of %q; qualify either all or none of the patterns of a "+
+				"path with a method", pkg, q, b, b, q)
+		}
+	}
+	return nil
+}
+
+// compose flattens the routers groups into routes and wraps every route with
+// the routers Mware functions, those added with Use being outermost. The order
+// of the routes is that documented by Compose.
+func (r *Router) compose() []Route {
+	return r.flatten(true)
+}
+
+// describe flattens the routers groups into routes as does compose but without
+// applying any Mware, nor so calling any Mware or PatternMware, such that the
+// routes may be described and checked but not served.
+func (r *Router) describe() []Route {
+	return r.flatten(false)
+}
+
+// flatten flattens the routers groups into routes, wrapping them with all of
+// their Mware only when wrap is set.
+func (r *Router) flatten(wrap bool) []Route {
+	n := len(r.routes)
+	for _, group := range r.groups {
+		n += group.size()
+	}
+	routes := append(make([]Route, 0, n), r.routes...)
 	for _, group := range r.groups {
-		r.routes = append(r.routes, group.compose()...)
+		routes = group.appendRoutes(routes, wrap)
+	}
+	if r.options {
+		routes = append(routes, optionsRoutes(routes)...)
+	}
+	for j := range routes {
+		routes[j].layers += len(r.byPath) + len(r.wrap) + len(r.use)
+		if !wrap {
+			continue
+		}
+		for _, mw := range r.byPath {
+			routes[j].fn = mw(routes[j].pattern, routes[j].fn)
+		}
+		routes[j].fn = r.wrapFn(routes[j].fn)
+		if !r.noHead {
+			routes[j].fn = autoHead(routes[j])
+		}
+	}
+	return routes
+}
+
+type headKey struct{}
+
+// autoHead returns the routes handler, wrapped so as to serve HEAD requests
+// when the route is restricted to GET but not HEAD. Being applied outside of
+// all middleware, the whole chain serves the request as a GET, the body being
+// discarded only once it has left the outermost Mware.
+func autoHead(route Route) http.HandlerFunc {
+	methods := route.info().Methods
+	get, head := false, false
+	for _, m := range methods {
+		get = get || m == http.MethodGet
+		head = head || m == http.MethodHead
+	}
+	next := route.fn
+	if !get || head {
+		return next
 	}
-	for j := range r.routes {
-		for _, fn := range r.wrap {
-			r.routes[j].fn = fn(r.routes[j].fn)
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodHead {
+			next(res, req)
+			return
 		}
-		r.mux.HandleFunc(r.routes[j].pattern, r.routes[j].fn)
+		w := &headWriter{ResponseWriter: newResponseWriter(res)}
+		get := req.WithContext(context.WithValue(req.Context(), headKey{}, true))
+		get.Method = http.MethodGet
+		next(w, get)
+		w.finish()
+	}
+}
+
+// optionsRoutes returns an OPTIONS route for each path whose routes are all
+// restricted to specific methods, responding with a 204 No Content and an Allow
+// header listing them. Paths for which an OPTIONS route exists, or which have a
+// route that accepts any method, are skipped.
+func optionsRoutes(routes []Route) []Route {
+	var paths []string
+	methods := make(map[string][]string)
+	skip := make(map[string]bool)
+	for _, route := range routes {
+		_, path := splitPattern(route.pattern)
+		info := route.info()
+		if len(info.Methods) == 0 {
+			skip[path] = true
+			continue
+		}
+		if _, ok := methods[path]; !ok {
+			paths = append(paths, path)
+		}
+		for _, m := range info.Methods {
+			if m == http.MethodOptions {
+				skip[path] = true
+			}
+			methods[path] = appendUnique(methods[path], m)
+			if m == http.MethodGet {
+				methods[path] = appendUnique(methods[path], http.MethodHead)
+			}
+		}
+	}
+	var opts []Route
+	for _, path := range paths {
+		if skip[path] {
+			continue
+		}
+		allow := strings.Join(append(methods[path], http.MethodOptions), ", ")
+		opts = append(opts, Route{
+			pattern:   http.MethodOptions + " " + path,
+			methods:   []string{http.MethodOptions},
+			generated: true,
+			fn: func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Allow", allow)
+				res.WriteHeader(http.StatusNoContent)
+			},
+		})
+	}
+	return opts
+}
+
+// appendUnique appends v to s if s does not already contain it.
+func appendUnique(s []string, v string) []string {
+	for _, e := range s {
+		if e == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
+
+// wrapFn wraps fn with the routers Mware functions.
+func (r *Router) wrapFn(fn http.HandlerFunc) http.HandlerFunc {
+	for _, mw := range r.wrap {
+		fn = mw(fn)
+	}
+	for i := len(r.use) - 1; i >= 0; i-- {
+		fn = r.use[i](fn)
+	}
+	return fn
+}
+
+// RouteInfo describes a composed route.
+type RouteInfo struct {
+	Pattern string
+	Name    string
+	// Methods are those to which the route is restricted, by Route.Method
+	// or by the method that qualifies its pattern.
+	Methods []string
+	// Middleware is the number of Mware that wrap the route.
+	Middleware int
+}
+
+// Flatten returns the Router's routes as Compose would register them, with all
+// groups flattened, their prefixes applied and every route wrapped with its
+// Mware, without registering them on any mux, such that they may be served by
+// Routes.Serve or by some other means. The Router's NotFound and
+// MethodNotAllowed handlers and its trailing slash handling are applied by its
+// mux and so are not included.
+func (r *Router) Flatten() Routes {
+	return r.compose()
+}
+
+// Walk calls fn with a description of every route that the Router contains, in
+// the order that Compose would register them, with all groups flattened and
+// their prefixes applied. The routes are not composed, no Mware being applied.
+func (r *Router) Walk(fn func(RouteInfo)) {
+	for _, route := range r.describe() {
+		fn(route.info())
+	}
+}
+
+// info returns a description of the route.
+func (r Route) info() RouteInfo {
+	methods := append([]string(nil), r.methods...)
+	if method, _ := splitPattern(r.pattern); method != "" && len(methods) == 0 {
+		methods = []string{method}
+	}
+	return RouteInfo{
+		Pattern:    r.pattern,
+		Name:       r.name,
+		Methods:    methods,
+		Middleware: r.layers,
+	}
+}
+
+// RouteList returns a description of every route that the Router contains, in
+// the order that Compose would register them, with all groups flattened and
+// their prefixes applied.
+func (r *Router) RouteList() []RouteInfo {
+	var list []RouteInfo
+	r.Walk(func(info RouteInfo) {
+		list = append(list, info)
+	})
+	return list
+}
+
+// URL returns the path of the Route with the given name, its wildcards, as in
+// "/items/{id}", being replaced in order by params. Each param is escaped, but
+// for the slashes that separate the segments of a "{name...}" wildcard.
+func (r *Router) URL(name string, params ...string) (string, error) {
+	for _, route := range r.RouteList() {
+		if route.Name == name {
+			return fillPattern(route.Pattern, params)
+		}
+	}
+	return "", fmt.Errorf("%s: unknown route name %q", pkg, name)
+}
+
+// fillPattern returns the path of pattern with its wildcards replaced by
+// params.
+func fillPattern(pattern string, params []string) (string, error) {
+	_, path := splitPattern(pattern)
+	if i := strings.Index(path, "/"); i > 0 {
+		path = path[i:]
+	}
+	var b strings.Builder
+	n := 0
+	for {
+		i := strings.Index(path, "{")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(path[i:], "}")
+		if j < 0 {
+			break
+		}
+		b.WriteString(path[:i])
+		switch wild := path[i : i+j+1]; {
+		case wild == "{$}":
+		case n >= len(params):
+			n++
+		case strings.HasSuffix(wild, "...}"):
+			// Each segment is escaped, the slashes between them kept.
+			segs := strings.Split(params[n], "/")
+			for i := range segs {
+				segs[i] = url.PathEscape(segs[i])
+			}
+			b.WriteString(strings.Join(segs, "/"))
+			n++
+		default:
+			b.WriteString(url.PathEscape(params[n]))
+			n++
+		}
+		path = path[i+j+1:]
+	}
+	b.WriteString(path)
+	if n != len(params) {
+		return "", fmt.Errorf("%s: pattern %q requires %d params got %d",
+			pkg, pattern, n, len(params))
 	}
-	return r.mux
+	return b.String(), nil
 }
@@ -0,0 +1,615 @@
+package srv
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writePattern responds with the pattern of the route that matched.
+func writePattern(res http.ResponseWriter, req *http.Request) {
+	res.Write([]byte(Pattern(req)))
+}
+
+// mapMux is a Muxer other than *http.ServeMux, matching exact patterns only.
+type mapMux map[string]http.Handler
+
+func (m mapMux) Handle(pattern string, h http.Handler) { m[pattern] = h }
+
+func TestPattern(t *testing.T) {
+	g := (&Group{}).Prefix("/api").Add(Handle("GET /items/{id}", writePattern))
+	r := NewRouter().Add(g)
+
+	rec := TestRouter(r, httptest.NewRequest("GET", "/api/items/7", nil))
+	if got, want := rec.Body.String(), "GET /api/items/{id}"; got != want {
+		t.Errorf("Build: got %q want %q", got, want)
+	}
+
+	mux := mapMux{}
+	if err := r.ComposeOn(mux); err != nil {
+		t.Fatal(err)
+	}
+	h, ok := mux["GET /api/items/{id}"]
+	if !ok {
+		t.Fatalf("ComposeOn: pattern not registered: %v", mux)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/items/7", nil))
+	if got, want := rec.Body.String(), "GET /api/items/{id}"; got != want {
+		t.Errorf("ComposeOn: got %q want %q", got, want)
+	}
+
+	rec = TestRoute(Handle("/x/{name}", writePattern), httptest.NewRequest("GET", "/x/y", nil))
+	if got, want := rec.Body.String(), "/x/{name}"; got != want {
+		t.Errorf("TestRoute: got %q want %q", got, want)
+	}
+}
+
+func TestPatternUnmatched(t *testing.T) {
+	r := NewRouter().Add(Handle("/a", writePattern)).NotFound(writePattern)
+	rec := TestRouter(r, httptest.NewRequest("GET", "/b", nil))
+	if got := rec.Body.String(); got != "" {
+		t.Errorf("NotFound: got pattern %q want none", got)
+	}
+}
+
+func TestShadowedPatterns(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(log.New(&buf, "", 0))
+	defer SetLogger(nil)
+
+	mixed := func() *Router {
+		return NewRouter().Add(Handle("GET /x", http.NotFound), Handle("/x", http.NotFound))
+	}
+	if _, err := mixed().ComposeE(); err == nil {
+		t.Error("ComposeE: mixed bare and method patterns accepted")
+	}
+	if err := mixed().Validate(); err == nil {
+		t.Error("Validate: mixed bare and method patterns accepted")
+	}
+	if _, err := mixed().Build(); err != nil {
+		t.Errorf("Build: %v", err)
+	}
+	if !strings.Contains(buf.String(), "warning") {
+		t.Errorf("Build: no warning logged, got %q", buf.String())
+	}
+
+	// The OPTIONS route generated for a bare route restricted by Method.
+	buf.Reset()
+	r := NewRouter().AutoOptions(true).Add(Handle("/x", http.NotFound).Method("GET"))
+	if _, err := r.ComposeE(); err != nil {
+		t.Errorf("AutoOptions: %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("AutoOptions: unexpected warning %q", buf.String())
+	}
+}
+
+func TestAutoHeadMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	body := strings.Repeat("hello world ", 200)
+	r := NewRouter().
+		Use(Logger(log.New(&logs, "", 0)), ETag(), Compress()).
+		Add(Handle("GET /x", func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Set("Content-Type", "text/plain")
+			res.Write([]byte(body))
+		}))
+	mux, err := r.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/x", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	get, head := serve("GET"), serve("HEAD")
+	if head.Code != http.StatusOK || head.Body.Len() != 0 {
+		t.Fatalf("HEAD: got status %d and %d bytes of body", head.Code, head.Body.Len())
+	}
+	for _, h := range []string{"ETag", "Content-Encoding", "Content-Type", "Vary"} {
+		if g, hd := get.Header().Get(h), head.Header().Get(h); g == "" || g != hd {
+			t.Errorf("%s: GET %q HEAD %q", h, g, hd)
+		}
+	}
+	if !strings.Contains(logs.String(), "HEAD /x 200") {
+		t.Errorf("Logger: HEAD request not logged as HEAD: %q", logs.String())
+	}
+}
+
+func TestOnPanicKeepsOuterHeaders(t *testing.T) {
+	route := Handle("/", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("X-Handler", "1")
+		panic("boom")
+	}).OnPanic(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte("fallback"))
+	}).Wrap(RequestID(), SecureHeaders(DefaultSecureConfig()))
+	rec := TestRoute(route, httptest.NewRequest("GET", "/", nil))
+	if rec.Body.String() != "fallback" {
+		t.Fatalf("got body %q want fallback", rec.Body)
+	}
+	h := rec.Header()
+	if h.Get("X-Request-ID") == "" || h.Get("Strict-Transport-Security") == "" {
+		t.Errorf("outer middleware headers lost: %v", h)
+	}
+	if h.Get("X-Handler") != "" {
+		t.Errorf("handler header kept: %v", h)
+	}
+}
+
+func TestPathValue(t *testing.T) {
+	writeID := func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(req.PathValue("id")))
+	}
+	r := NewRouter().
+		Add(Handle("GET /x/{id}", writeID)).
+		Add((&Group{}).Prefix("/api").Add(Handle("GET /y/{id}", writeID)))
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/x/7", 200, "7"},
+		{"GET", "/api/y/8", 200, "8"},
+		{"POST", "/x/7", 405, ""},
+	}
+	for _, tt := range tests {
+		rec := TestRouter(r, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: got status %d want %d", tt.method, tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s %s: got %q want %q", tt.method, tt.path, rec.Body, tt.body)
+		}
+	}
+}
+
+// trace returns an Mware that records in calls its name as it is entered and
+// left.
+func trace(calls *[]string, name string) Mware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(res http.ResponseWriter, req *http.Request) {
+			*calls = append(*calls, name)
+			next(res, req)
+			*calls = append(*calls, "/"+name)
+		}
+	}
+}
+
+// checkCalls reports an error unless got equals want.
+func checkCalls(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got order %v want %v", got, want)
+	}
+}
+
+func TestRouterUseOrder(t *testing.T) {
+	var calls []string
+	r := NewRouter().
+		Use(trace(&calls, "a"), trace(&calls, "b")).
+		Use(trace(&calls, "c")).
+		Add(Handle("/", func(http.ResponseWriter, *http.Request) {
+			calls = append(calls, "h")
+		}))
+	TestRouter(r, httptest.NewRequest("GET", "/", nil))
+	checkCalls(t, calls, "a", "b", "c", "h", "/c", "/b", "/a")
+}
+
+// largeRouter returns a Router of n groups each of n routes, nested under a
+// common base group that is forked for each.
+func largeRouter(n int) *Router {
+	base := (&Group{}).Wrap(Chain())
+	r := NewRouter().Use(Chain())
+	for i := 0; i < n; i++ {
+		g := *base
+		g.Prefix(fmt.Sprintf("/g%d", i))
+		for j := 0; j < n; j++ {
+			g.Add(Handle(fmt.Sprintf("GET /r%d/{id}", j), http.NotFound).Name(fmt.Sprintf("g%d.r%d", i, j)))
+		}
+		r.Add(&g)
+	}
+	return r
+}
+
+func TestConcurrentBuild(t *testing.T) {
+	base := (&Group{}).Add(Handle("/x", http.NotFound))
+	groups := make([]*Group, 16)
+	var wg sync.WaitGroup
+	for i := range groups {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g := *base
+			g.Prefix(fmt.Sprintf("/f%d", i))
+			for j := 0; j < 50; j++ {
+				g.Add(Handle(fmt.Sprintf("/%d/%d", i, j), http.NotFound))
+			}
+			groups[i] = &g
+		}(i)
+	}
+	wg.Wait()
+
+	r := largeRouter(20)
+	for _, g := range groups {
+		r.Add(g)
+	}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Build(); err != nil {
+				t.Error(err)
+			}
+			if n := len(r.RouteList()); n != 20*20+16*51 {
+				t.Errorf("RouteList: got %d routes", n)
+			}
+			if _, err := r.URL("g3.r4", "7"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGroupForkIsolation(t *testing.T) {
+	var calls []string
+	base := (&Group{}).
+		Add(Handle("/base", http.NotFound), Handle("/spare", http.NotFound)).
+		Wrap(trace(&calls, "base"))
+	a, b := *base, *base
+	a.Add(Handle("/a", http.NotFound)).Wrap(trace(&calls, "a"))
+	b.Add(Handle("/b", http.NotFound)).Wrap(trace(&calls, "b"))
+
+	patterns := func(g *Group) []string {
+		var list []string
+		NewRouter().Add(g).Walk(func(info RouteInfo) {
+			list = append(list, info.Pattern)
+		})
+		return list
+	}
+	checkCalls(t, patterns(base), "/base", "/spare")
+	checkCalls(t, patterns(&a), "/base", "/spare", "/a")
+	checkCalls(t, patterns(&b), "/base", "/spare", "/b")
+
+	TestRouter(NewRouter().Add(&b), httptest.NewRequest("GET", "/a", nil))
+	TestRouter(NewRouter().Add(&b), httptest.NewRequest("GET", "/b", nil))
+	checkCalls(t, calls, "b", "base", "/base", "/b")
+}
+
+func TestRouteOrder(t *testing.T) {
+	sub := NewRouter().Add(Handle("/s", http.NotFound))
+	inner := (&Group{}).Prefix("/in").Add(Handle("/c", http.NotFound))
+	r := NewRouter().
+		Add((&Group{}).Prefix("/g").
+			Add(inner, Handle("/b", http.NotFound)).
+			NotFound(http.NotFound)).
+		Add(Handle("/a", http.NotFound)).
+		Add((&Group{}).Add(Handle("/d", http.NotFound))).
+		Mount("/m", sub)
+	want := []string{"/a", "/m/s", "/g/b", "/g/in/c", "/g/", "/d"}
+	for i := 0; i < 3; i++ {
+		var got []string
+		for _, info := range r.RouteList() {
+			got = append(got, info.Pattern)
+		}
+		checkCalls(t, got, want...)
+		got = got[:0]
+		for _, route := range r.Flatten() {
+			got = append(got, route.Pattern())
+		}
+		checkCalls(t, got, want...)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	tracePattern := func(name string) PatternMware {
+		return func(pattern string, next http.HandlerFunc) http.HandlerFunc {
+			return trace(&calls, name)(next)
+		}
+	}
+	route := Handle("/x", func(http.ResponseWriter, *http.Request) {
+		calls = append(calls, "h")
+	}).Wrap(trace(&calls, "route1"), trace(&calls, "route2"))
+	inner := (&Group{}).Prefix("/in").
+		Wrap(trace(&calls, "inner1"), trace(&calls, "inner2")).
+		Add(route)
+	outer := (&Group{}).Prefix("/out").
+		Wrap(trace(&calls, "outer1"), trace(&calls, "outer2")).
+		Add(inner)
+	r := NewRouter().
+		Wrap(trace(&calls, "wrap1"), trace(&calls, "wrap2")).
+		WrapPattern(tracePattern("pattern1"), tracePattern("pattern2")).
+		Use(trace(&calls, "use1"), trace(&calls, "use2")).
+		Add(outer)
+	TestRouter(r, httptest.NewRequest("GET", "/out/in/x", nil))
+	layers := []string{
+		"use1", "use2",
+		"wrap2", "wrap1",
+		"pattern2", "pattern1",
+		"outer2", "outer1",
+		"inner2", "inner1",
+		"route2", "route1",
+	}
+	want := append([]string(nil), layers...)
+	want = append(want, "h")
+	for i := len(layers) - 1; i >= 0; i-- {
+		want = append(want, "/"+layers[i])
+	}
+	checkCalls(t, calls, want...)
+}
+
+func TestRouterWrapOnce(t *testing.T) {
+	var calls []string
+	h := func(http.ResponseWriter, *http.Request) { calls = append(calls, "h") }
+	sub := NewRouter().Add(Handle("/s", h))
+	r := NewRouter().
+		Wrap(trace(&calls, "router")).
+		AutoOptions(true).
+		Add(Handle("/a", h)).
+		Add(Handle("GET /m", h)).
+		Add((&Group{}).Prefix("/g").Add(Handle("/b", h)).NotFound(h).Wrap(trace(&calls, "group"))).
+		Mount("/sub", sub).
+		NotFound(h)
+	tests := []struct {
+		method, path string
+		want         []string
+	}{
+		{"GET", "/a", []string{"router", "h", "/router"}},
+		{"GET", "/g/b", []string{"router", "group", "h", "/group", "/router"}},
+		{"GET", "/g/missing", []string{"router", "group", "h", "/group", "/router"}},
+		{"GET", "/sub/s", []string{"router", "h", "/router"}},
+		{"GET", "/missing", []string{"router", "h", "/router"}},
+		{"OPTIONS", "/m", []string{"router", "/router"}},
+		{"HEAD", "/m", []string{"router", "h", "/router"}},
+	}
+	for _, tt := range tests {
+		calls = nil
+		TestRouter(r, httptest.NewRequest(tt.method, tt.path, nil))
+		checkCalls(t, calls, tt.want...)
+	}
+}
+
+// deepRouter returns a Router of n routes, each in its own group nested depth
+// groups deep, every group adding an Mware.
+func deepRouter(n, depth int) *Router {
+	r := NewRouter().Use(Chain())
+	for i := 0; i < n; i++ {
+		g := (&Group{}).Add(Handle("/r", http.NotFound))
+		for d := 0; d < depth; d++ {
+			g = (&Group{}).Prefix(fmt.Sprintf("/d%d", d)).Wrap(Chain()).Add(g)
+		}
+		r.Add(g.Prefix(fmt.Sprintf("/n%d", i)))
+	}
+	return r
+}
+
+func BenchmarkBuild(b *testing.B) {
+	for _, bm := range []struct {
+		name string
+		r    *Router
+	}{
+		{"wide", largeRouter(25)},
+		{"deep", deepRouter(50, 10)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bm.r.Build(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// okHandler is an http.Handler that writes nothing.
+type okHandler struct{}
+
+func (okHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+func BenchmarkDispatch(b *testing.B) {
+	fn := func(http.ResponseWriter, *http.Request) {}
+	for _, bm := range []struct {
+		name string
+		h    any
+	}{
+		{"Handler", okHandler{}},
+		{"HandlerFunc", http.HandlerFunc(fn)},
+		{"func", fn},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			mux, err := NewRouter().Add(Handle("GET /items/{id}", bm.h)).Build()
+			if err != nil {
+				b.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "/items/7", nil)
+			rec := httptest.NewRecorder()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mux.ServeHTTP(rec, req)
+			}
+		})
+	}
+}
+
+func TestHandleNil(t *testing.T) {
+	var (
+		handler     http.Handler
+		handlerFunc http.HandlerFunc
+		fn          func(http.ResponseWriter, *http.Request)
+		ptr         *http.ServeMux
+	)
+	for _, h := range []any{nil, handler, handlerFunc, fn, ptr, http.Handler(ptr)} {
+		_, err := HandleE("/nil", h)
+		if err == nil {
+			t.Errorf("%T: nil handler accepted", h)
+			continue
+		}
+		if !strings.Contains(err.Error(), `"/nil"`) {
+			t.Errorf("%T: error does not name the pattern: %v", h, err)
+		}
+	}
+}
+
+func TestGroupReused(t *testing.T) {
+	var calls []string
+	g := (&Group{}).Wrap(trace(&calls, "g")).Add(Handle("/x", http.NotFound))
+	r1 := NewRouter().Wrap(trace(&calls, "r1")).Add(g)
+	g.Wrap(trace(&calls, "late"))
+	r2 := NewRouter().Wrap(trace(&calls, "r2")).Add(g)
+
+	for _, tt := range []struct {
+		r    *Router
+		want []string
+	}{
+		{r1, []string{"r1", "g", "/g", "/r1"}},
+		{r2, []string{"r2", "late", "g", "/g", "/late", "/r2"}},
+		{r1, []string{"r1", "g", "/g", "/r1"}},
+	} {
+		calls = nil
+		TestRouter(tt.r, httptest.NewRequest("GET", "/x", nil))
+		checkCalls(t, calls, tt.want...)
+		if got, want := tt.r.RouteList()[0].Middleware, len(tt.want)/2; got != want {
+			t.Errorf("Middleware: got %d want %d", got, want)
+		}
+	}
+}
+
+func TestMethodRepeated(t *testing.T) {
+	var calls []string
+	route := Handle("/x", func(http.ResponseWriter, *http.Request) {
+		calls = append(calls, "h")
+	}).Method("GET").Wrap(trace(&calls, "mw")).Method("post")
+	for _, tt := range []struct {
+		method string
+		code   int
+		calls  []string
+	}{
+		{"GET", 200, []string{"mw", "h", "/mw"}},
+		{"POST", 200, []string{"mw", "h", "/mw"}},
+		{"PUT", 405, []string{"mw", "/mw"}},
+	} {
+		calls = nil
+		rec := TestRoute(route, httptest.NewRequest(tt.method, "/x", nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: got status %d want %d", tt.method, rec.Code, tt.code)
+		}
+		checkCalls(t, calls, tt.calls...)
+		if tt.code == 405 && rec.Header().Get("Allow") != "GET, POST" {
+			t.Errorf("%s: Allow %q", tt.method, rec.Header().Get("Allow"))
+		}
+	}
+	if got := route.MiddlewareCount(); got != 1 {
+		t.Errorf("MiddlewareCount: got %d want 1", got)
+	}
+}
+
+func TestIntrospectionDoesNotCompose(t *testing.T) {
+	applied := 0
+	count := func(next http.HandlerFunc) http.HandlerFunc {
+		applied++
+		return next
+	}
+	countPattern := func(pattern string, next http.HandlerFunc) http.HandlerFunc {
+		applied++
+		return next
+	}
+	r := NewRouter().Use(count).Wrap(count).WrapPattern(countPattern).
+		Add((&Group{}).Prefix("/g").Wrap(count).
+			Add(Handle("GET /items/{id}", http.NotFound).Name("item")))
+	r.Walk(func(RouteInfo) {})
+	if list := r.RouteList(); len(list) != 1 || list[0].Middleware != 4 {
+		t.Errorf("RouteList: %+v", list)
+	}
+	if u, err := r.URL("item", "7"); err != nil || u != "/g/items/7" {
+		t.Errorf("URL: got %q %v", u, err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Error(err)
+	}
+	if applied != 0 {
+		t.Errorf("Mware applied %d times without composing", applied)
+	}
+	if _, err := r.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if applied != 4 {
+		t.Errorf("Build: Mware applied %d times want 4", applied)
+	}
+}
+
+func TestPrefixPattern(t *testing.T) {
+	tests := []struct{ prefix, pattern, want string }{
+		{"/api", "/x", "/api/x"},
+		{"/api/", "/x/", "/api/x/"},
+		{"/api", "/", "/api/"},
+		{"/api", "GET /x/{id}", "GET /api/x/{id}"},
+		{"/api", "example.com/x", "example.com/api/x"},
+		{"/api", "example.com/", "example.com/api/"},
+		{"/api", "GET example.com/x", "GET example.com/api/x"},
+		{"", "example.com/x", "example.com/x"},
+	}
+	for _, tt := range tests {
+		if got := prefixPattern(tt.prefix, tt.pattern); got != tt.want {
+			t.Errorf("prefixPattern(%q, %q): got %q want %q", tt.prefix, tt.pattern, got, tt.want)
+		}
+	}
+
+	r := NewRouter().Add((&Group{}).Prefix("/api").Add(Handle("example.com/x", writePattern)))
+	rec := TestRouter(r, httptest.NewRequest("GET", "http://example.com/api/x", nil))
+	if got, want := rec.Body.String(), "example.com/api/x"; got != want {
+		t.Errorf("host pattern: got %q want %q", got, want)
+	}
+}
+
+func TestRedirectSlashEscaped(t *testing.T) {
+	tests := []struct {
+		mode       SlashMode
+		route, req string
+		want       string
+	}{
+		{SlashStrip, "/a%3Fb", "/a%3Fb/?q=1", "/a%3Fb?q=1"},
+		{SlashStrip, "/x/{name}", "/x/c%2Fd/", "/x/c%2Fd"},
+	}
+	for _, tt := range tests {
+		r := NewRouter().RedirectTrailingSlash(tt.mode).Add(Handle(tt.route, http.NotFound))
+		rec := TestRouter(r, httptest.NewRequest("GET", tt.req, nil))
+		if rec.Code != http.StatusMovedPermanently {
+			t.Errorf("%s: got status %d", tt.req, rec.Code)
+			continue
+		}
+		if got := rec.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s: redirected to %q want %q", tt.req, got, tt.want)
+		}
+	}
+}
+
+func TestURLEscaped(t *testing.T) {
+	r := NewRouter().
+		Add(Handle("/f/{path...}", http.NotFound).Name("f")).
+		Add(Handle("/i/{id}", http.NotFound).Name("i"))
+	tests := []struct {
+		name, param, want string
+	}{
+		{"f", "a/b c", "/f/a/b%20c"},
+		{"f", "a?/b#c/", "/f/a%3F/b%23c/"},
+		{"i", "a/b c", "/i/a%2Fb%20c"},
+	}
+	for _, tt := range tests {
+		got, err := r.URL(tt.name, tt.param)
+		if err != nil || got != tt.want {
+			t.Errorf("URL(%q, %q): got %q %v want %q", tt.name, tt.param, got, err, tt.want)
+		}
+	}
+}
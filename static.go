@@ -0,0 +1,171 @@
+package srv
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// StaticOption configures the routes returned by Static.
+type StaticOption func(*staticConfig)
+
+type staticConfig struct {
+	noList   bool
+	cache    string
+	notFound string
+	index    string
+}
+
+// NoListing disables directory listings, directories without an index.html
+// respond with 404 Not Found.
+func NoListing() StaticOption {
+	return func(c *staticConfig) { c.noList = true }
+}
+
+// StaticCacheControl sets the Cache-Control header sent with every file served.
+func StaticCacheControl(value string) StaticOption {
+	return func(c *staticConfig) { c.cache = value }
+}
+
+// NotFoundFile sets a file, relative to the served directory, that is served
+// with a 404 Not Found status in place of any file that does not exist.
+func NotFoundFile(name string) StaticOption {
+	return func(c *staticConfig) { c.notFound = name }
+}
+
+// IndexFallback sets a file, relative to the served directory, that is served
+// with a 200 OK in place of any file that does not exist, as required by
+// single page applications that perform their own routing.
+func IndexFallback(name string) StaticOption {
+	return func(c *staticConfig) { c.index = name }
+}
+
+// Static returns a Route that serves the files in dir under pattern, the path
+// of the pattern, including any prefix given by a Group or Mount, being
+// stripped from the request before the file is looked up.
+// Requests are confined to dir, paths containing ".." elements that would
+// escape it being rejected by http.Dir. Files are served with a Last-Modified
+// header and conditional requests, If-Modified-Since, are answered with a 304
+// Not Modified, the package's middleware passing the status and validators
+// through unchanged.
+func Static(pattern, dir string, opts ...StaticOption) *Route {
+	return fileRoute(pattern, http.Dir(dir), opts)
+}
+
+// SPA returns a Route for a single page application, serving the files in dir
+// under pattern and in place of any that do not exist serving indexFile with a
+// 200 OK. Routes registered at other patterns are unaffected.
+func SPA(pattern, dir, indexFile string) *Route {
+	return Static(pattern, dir, NoListing(), IndexFallback(indexFile))
+}
+
+// StaticFS returns a Route that serves the files of fsys, such as an embed.FS,
+// under pattern, as does http.FileServerFS, the path of the pattern being
+// stripped from the request before the file is looked up.
+func StaticFS(pattern string, fsys fs.FS, opts ...StaticOption) *Route {
+	return fileRoute(pattern, http.FS(fsys), opts)
+}
+
+// fileRoute returns a Route serving the files of fsys under pattern.
+func fileRoute(pattern string, fsys http.FileSystem, opts []StaticOption) *Route {
+	var cfg staticConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.noList {
+		fsys = noListFS{fsys}
+	}
+	if !strings.Contains(pattern, "{") && !strings.HasSuffix(pattern, "/") {
+		pattern += "/"
+	}
+	server := http.FileServer(fsys)
+	fn := func(res http.ResponseWriter, req *http.Request) {
+		if cfg.cache != "" {
+			res.Header().Set("Cache-Control", cfg.cache)
+		}
+		if cfg.index != "" || cfg.notFound != "" {
+			if !exists(fsys, req.URL.Path) {
+				if cfg.index != "" {
+					serveStatus(res, req, fsys, cfg.index, http.StatusOK)
+				} else {
+					serveStatus(res, req, fsys, cfg.notFound, http.StatusNotFound)
+				}
+				return
+			}
+		}
+		server.ServeHTTP(res, req)
+	}
+	return &Route{
+		pattern: pattern,
+		fn:      stripPattern(pattern, http.HandlerFunc(fn)),
+	}
+}
+
+// exists reports whether the named file may be opened from fsys.
+func exists(fsys http.FileSystem, name string) bool {
+	f, err := fsys.Open(path.Clean("/" + name))
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// serveStatus writes the named file from fsys with the given status code, a
+// 200 OK being served with http.ServeContent so that the Last-Modified header
+// is set and conditional requests are honoured.
+func serveStatus(res http.ResponseWriter, req *http.Request, fsys http.FileSystem, name string, code int) {
+	f, err := fsys.Open(path.Clean("/" + name))
+	if err != nil {
+		http.NotFound(res, req)
+		return
+	}
+	defer f.Close()
+	if code == http.StatusOK {
+		if stat, err := f.Stat(); err == nil && !stat.IsDir() {
+			http.ServeContent(res, req, stat.Name(), stat.ModTime(), f)
+			return
+		}
+	}
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		res.Header().Set("Content-Type", ct)
+	}
+	res.WriteHeader(code)
+	if req.Method != http.MethodHead {
+		io.Copy(res, f)
+	}
+}
+
+// noListFS is an http.FileSystem that refuses to open directories that have
+// no index.html.
+type noListFS struct {
+	fs http.FileSystem
+}
+
+func (n noListFS) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		index, err := n.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			f.Close()
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fs.ErrNotExist
+			}
+			return nil, err
+		}
+		index.Close()
+	}
+	return f, nil
+}
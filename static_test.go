@@ -0,0 +1,103 @@
+package srv
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// staticDir returns a temporary directory holding a.txt and index.html.
+func staticDir(t *testing.T) string {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"a.txt":      "file a",
+		"index.html": "index",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStaticPrefixed(t *testing.T) {
+	dir := staticDir(t)
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("file a")}}
+	sub := NewRouter().Add(Static("/assets/", dir))
+	r := NewRouter().
+		Add((&Group{}).Prefix("/api").Add(Static("/assets/", dir))).
+		Add((&Group{}).Prefix("/fs").Add(StaticFS("/files/", fsys))).
+		Add((&Group{}).Prefix("/app").Add(SPA("/", dir, "index.html"))).
+		Mount("/v1", sub)
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/assets/a.txt", 200, "file a"},
+		{"/fs/files/a.txt", 200, "file a"},
+		{"/v1/assets/a.txt", 200, "file a"},
+		{"/app/a.txt", 200, "file a"},
+		{"/app/some/route", 200, "index"},
+		{"/api/assets/missing.txt", 404, ""},
+	}
+	for _, tt := range tests {
+		rec := TestRouter(r, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: got status %d want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: got body %q want %q", tt.path, rec.Body, tt.body)
+		}
+	}
+}
+
+func TestStaticNotModified(t *testing.T) {
+	dir := staticDir(t)
+	mod := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "a.txt"), mod, mod); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("file a"), ModTime: mod}}
+	r := NewRouter().
+		Use(Recover(), Logger(log.New(io.Discard, "", 0)), ETag(), Compress(CompressMinSize(0))).
+		Add(Static("/static/", dir, StaticCacheControl("max-age=60"))).
+		Add(StaticFS("/fs/", fsys, StaticCacheControl("max-age=60")))
+	lastModified := mod.Format(http.TimeFormat)
+	for _, path := range []string{"/static/a.txt", "/fs/a.txt"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := TestRouter(r, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d", path, rec.Code)
+		}
+		if got := rec.Header().Get("Last-Modified"); got != lastModified {
+			t.Errorf("%s: Last-Modified %q want %q", path, got, lastModified)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "max-age=60" {
+			t.Errorf("%s: Cache-Control %q", path, got)
+		}
+
+		req.Header.Set("If-Modified-Since", lastModified)
+		rec = TestRouter(r, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("%s: If-Modified-Since: got status %d and %d bytes",
+				path, rec.Code, rec.Body.Len())
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%s: 304 with Content-Encoding %q", path, got)
+		}
+
+		req.Header.Set("If-Modified-Since", mod.Add(-time.Hour).Format(http.TimeFormat))
+		if rec = TestRouter(r, req); rec.Code != http.StatusOK {
+			t.Errorf("%s: modified since: got status %d", path, rec.Code)
+		}
+	}
+}
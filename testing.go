@@ -0,0 +1,47 @@
+package srv
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+// TestRoute serves req with the Route's handler, including its middleware, and
+// returns the recorded response. The route is registered upon a new mux so that
+// path values and its Pattern are available to the handler, a request that
+// does not match its pattern receiving the mux's response.
+func TestRoute(r *Route, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc(r.pattern, withPattern(r.pattern, r.fn))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestRouter builds the Router and serves req with the resulting mux, returning
+// the recorded response. It panics if the Router fails to build.
+func TestRouter(r *Router, req *http.Request) *httptest.ResponseRecorder {
+	mux, err := r.Build()
+	if err != nil {
+		panic(err)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// NewTestServer builds the Router and returns a running httptest.Server that
+// serves the resulting mux, for tests that make real HTTP requests against the
+// routes. The caller should close the server when done:
+//
+//	ts := srv.NewTestServer(r)
+//	defer ts.Close()
+//	res, err := http.Get(ts.URL + "/health")
+//
+// It panics if the Router fails to build.
+func NewTestServer(r *Router) *httptest.Server {
+	mux, err := r.Build()
+	if err != nil {
+		panic(err)
+	}
+	return httptest.NewServer(mux)
+}
@@ -0,0 +1,225 @@
+package srv
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// wrapper is implemented by every http.ResponseWriter wrapper of the package,
+// so that middleware never hides the capabilities of the underlying writer from
+// streaming handlers and those that hijack the connection, such as websockets,
+// nor from an http.ResponseController, to which FlushError reports whether the
+// underlying writer could be flushed.
+type wrapper interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	FlushError() error
+	Unwrap() http.ResponseWriter
+}
+
+var (
+	_ aborter = (*ResponseWriter)(nil)
+	_ aborter = (*compressWriter)(nil)
+	_ aborter = (*etagWriter)(nil)
+	_ aborter = (*sessionWriter)(nil)
+
+	_ wrapper = (*ResponseWriter)(nil)
+	_ wrapper = (*compressWriter)(nil)
+	_ wrapper = (*etagWriter)(nil)
+	_ wrapper = (*maxBodyWriter)(nil)
+	_ wrapper = (*sessionWriter)(nil)
+	_ wrapper = (*headWriter)(nil)
+	_ wrapper = (*headersWriter)(nil)
+)
+
+// ResponseWriter wraps an http.ResponseWriter recording the status code and
+// the number of bytes written to the response, the underlying writers Flush and
+// Hijack methods are forwarded when it implements them.
+//
+// Middleware that need to observe the response should obtain their writer with
+// NewResponseWriter, passing it on to the next handler in place of the writer
+// they were given. Should an outer middleware already have done so the same
+// ResponseWriter is returned and shared rather than stacking another wrapper.
+// Middleware that replace the body of the response, such as Compress, wrap
+// the writer in a type of their own, hiding any ResponseWriter above them; a
+// middleware inside them then starts a new one, recording the response as it
+// is before being replaced.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int
+	aborted bool
+}
+
+// NewResponseWriter returns res if it is already a *ResponseWriter, else a new
+// ResponseWriter wrapping res.
+func NewResponseWriter(res http.ResponseWriter) *ResponseWriter {
+	if w, ok := res.(*ResponseWriter); ok {
+		return w
+	}
+	return newResponseWriter(res)
+}
+
+// newResponseWriter always returns a new ResponseWriter, for those wrappers of
+// the package that embed one and keep their own count.
+func newResponseWriter(res http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: res}
+}
+
+func (r *ResponseWriter) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *ResponseWriter) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+// Status returns the status code written to the response, or 200 if the
+// handler has written a body without first writing a header.
+func (r *ResponseWriter) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// BytesWritten returns the number of bytes of the body written to the
+// response.
+func (r *ResponseWriter) BytesWritten() int {
+	return r.size
+}
+
+// Flush flushes the underlying writer, which implicitly writes the header.
+func (r *ResponseWriter) Flush() {
+	r.FlushError()
+}
+
+// FlushError is as Flush but returns the error of the underlying writer, or
+// http.ErrNotSupported when it can not be flushed, such that it is reported by
+// http.ResponseController.
+func (r *ResponseWriter) FlushError() error {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: %T does not implement http.Hijacker",
+			pkg, r.ResponseWriter)
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use by
+// http.ResponseController.
+func (r *ResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *ResponseWriter) abort()          { r.aborted = true }
+func (r *ResponseWriter) isAborted() bool { return r.aborted }
+
+// probe is an http.ResponseWriter that records the header and status code
+// written to it, discarding the body.
+type probe struct {
+	header http.Header
+	status int
+}
+
+func (p *probe) Header() http.Header { return p.header }
+
+func (p *probe) WriteHeader(code int) {
+	if p.status == 0 {
+		p.status = code
+	}
+}
+
+func (p *probe) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+// headWriter discards the body of a response to a HEAD request, delaying the
+// header until the handler returns so that the Content-Length of the discarded
+// body may be set.
+type headWriter struct {
+	*ResponseWriter
+	code int
+	sent bool
+}
+
+func (w *headWriter) WriteHeader(code int) {
+	if w.sent || code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.size == 0 && len(b) > 0 && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(b))
+	}
+	w.size += len(b)
+	return len(b), nil
+}
+
+// finish writes the header, setting the Content-Length if it is not set.
+func (w *headWriter) finish() {
+	if w.sent {
+		return
+	}
+	w.sent = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	h := w.Header()
+	if h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" &&
+		w.code != http.StatusNoContent && w.code != http.StatusNotModified {
+		h.Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
+
+// Flush writes the header, the Content-Length then being unknown.
+func (w *headWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *headWriter) FlushError() error {
+	if !w.sent {
+		w.sent = true
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+	return w.ResponseWriter.FlushError()
+}
+
+func (w *headWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.sent = true
+	return w.ResponseWriter.Hijack()
+}
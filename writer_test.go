@@ -0,0 +1,42 @@
+package srv
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// plainWriter is an http.ResponseWriter that can not be flushed.
+type plainWriter struct {
+	header http.Header
+}
+
+func (w *plainWriter) Header() http.Header         { return w.header }
+func (w *plainWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *plainWriter) WriteHeader(int)             {}
+
+func TestFlushError(t *testing.T) {
+	var flushErr error
+	mux, err := NewRouter().Use(stack(io.Discard)...).Add(Handle("GET /x", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/plain")
+		io.WriteString(res, "data")
+		flushErr = http.NewResponseController(res).Flush()
+	})).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{"GET", "HEAD"} {
+		req := httptest.NewRequest(method, "/x", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		mux.ServeHTTP(&plainWriter{header: http.Header{}}, req)
+		if !errors.Is(flushErr, http.ErrNotSupported) {
+			t.Errorf("%s: unflushable writer: got %v want ErrNotSupported", method, flushErr)
+		}
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+		if flushErr != nil {
+			t.Errorf("%s: recorder: got %v", method, flushErr)
+		}
+	}
+}